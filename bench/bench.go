@@ -1,14 +1,13 @@
 package main
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
 	"github.com/jakecoffman/cpebiten"
-	"log"
 	"math/rand"
 )
 
-func NewGame() *cpebiten.Game {
+// NewGame builds the scene, taking its randomness from rng.
+func NewGame(rng *rand.Rand) *cpebiten.Game {
 	space := cp.NewSpace()
 	space.Iterations = 10
 	space.SetGravity(cp.Vector{0, 100})
@@ -33,27 +32,22 @@ func NewGame() *cpebiten.Game {
 	}
 
 	for i := 0; i < 1000; i++ {
-		pos := randUnitCircle().Mult(180).Add(cp.Vector{cpebiten.ScreenWidth/2 + 10, cpebiten.ScreenHeight / 2})
+		pos := randUnitCircle(rng).Mult(180).Add(cp.Vector{cpebiten.ScreenWidth/2 + 10, cpebiten.ScreenHeight / 2})
 		const radius = 5
 		const mass = radius * radius / 25.0
 		cpebiten.AddCircle(space, pos, mass, radius)
 	}
 
-	return cpebiten.NewGame(space, 60)
+	game := cpebiten.NewGame(space, 60)
+	// keep the last 5 seconds around so Backspace can rewind
+	game.History = cpebiten.NewHistory(5 * 60)
+	return game
 }
 
-func randUnitCircle() cp.Vector {
-	v := cp.Vector{rand.Float64()*2.0 - 1.0, rand.Float64()*2.0 - 1.0}
+func randUnitCircle(rng *rand.Rand) cp.Vector {
+	v := cp.Vector{rng.Float64()*2.0 - 1.0, rng.Float64()*2.0 - 1.0}
 	if v.LengthSq() < 1.0 {
 		return v
 	}
-	return randUnitCircle()
-}
-
-func main() {
-	ebiten.SetWindowSize(cpebiten.ScreenWidth, cpebiten.ScreenHeight)
-	ebiten.SetWindowTitle("Benchmark")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
-	}
+	return randUnitCircle(rng)
 }
@@ -0,0 +1,20 @@
+//go:build !headless
+
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jakecoffman/cpebiten"
+)
+
+func main() {
+	ebiten.SetWindowSize(cpebiten.ScreenWidth, cpebiten.ScreenHeight)
+	ebiten.SetWindowTitle("Benchmark")
+	if err := ebiten.RunGame(NewGame(rand.New(rand.NewSource(time.Now().UnixNano())))); err != nil {
+		log.Fatal(err)
+	}
+}
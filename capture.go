@@ -0,0 +1,220 @@
+package cpebiten
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FrameWriter stores captured frames. The image passed to WriteFrame may be reused or changed
+// afterwards, so it must be copied or encoded before WriteFrame returns.
+type FrameWriter interface {
+	WriteFrame(img image.Image) error
+	Close() error
+}
+
+// GIFWriter encodes frames into an animated GIF as they are written, so a long capture doesn't
+// pile up in memory.
+type GIFWriter struct {
+	w      io.Writer
+	closer io.Closer
+	// Delay is the time each frame is shown, in 100ths of a second.
+	Delay int
+
+	started bool
+	frame   *image.Paletted
+	buf     bytes.Buffer
+}
+
+// NewGIFWriter creates a writer that encodes to w. delay is the time each frame is shown in
+// 100ths of a second, so capturing every nth tick at a given tick rate should use
+// 100*n/ticksPerSecond.
+func NewGIFWriter(w io.Writer, delay int) *GIFWriter {
+	return &GIFWriter{w: w, Delay: delay}
+}
+
+// CreateGIF is a convenience for NewGIFWriter that writes to a file.
+func CreateGIF(path string, delay int) (*GIFWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGIFWriter(f, delay)
+	g.closer = f
+	return g, nil
+}
+
+// gifHeaderSize is the size of the header image/gif writes before the first frame of a GIF
+// with no global color table and a single frame, which gets no looping extension.
+const gifHeaderSize = 13
+
+func (g *GIFWriter) WriteFrame(img image.Image) error {
+	if g.frame == nil || g.frame.Rect != img.Bounds() {
+		g.frame = image.NewPaletted(img.Bounds(), palette.Plan9)
+	}
+	draw.Draw(g.frame, g.frame.Rect, img, img.Bounds().Min, draw.Src)
+
+	// image/gif can only encode a whole animation at once, so encode the frame on its own and
+	// keep just its image block, between the header and the trailer
+	g.buf.Reset()
+	anim := gif.GIF{Image: []*image.Paletted{g.frame}, Delay: []int{g.Delay}}
+	if err := gif.EncodeAll(&g.buf, &anim); err != nil {
+		return err
+	}
+	b := g.buf.Bytes()
+	if len(b) <= gifHeaderSize+1 || string(b[:6]) != "GIF89a" || b[len(b)-1] != 0x3b {
+		return errors.New("unexpected GIF encoding")
+	}
+
+	if !g.started {
+		if err := g.writeHeader(img.Bounds().Max); err != nil {
+			return err
+		}
+		g.started = true
+	}
+	_, err := g.w.Write(b[gifHeaderSize : len(b)-1])
+	return err
+}
+
+// writeHeader starts an animation of the given size that loops forever.
+func (g *GIFWriter) writeHeader(size image.Point) error {
+	header := []byte("GIF89a")
+	header = append(header,
+		byte(size.X), byte(size.X>>8), byte(size.Y), byte(size.Y>>8),
+		0, // every frame has its own color table
+		0, // background color index
+		0, // pixel aspect ratio
+	)
+	header = append(header, 0x21, 0xff, 0x0b) // application extension
+	header = append(header, "NETSCAPE2.0"...)
+	header = append(header, 3, 1, 0, 0, 0) // loop count 0, forever
+	_, err := g.w.Write(header)
+	return err
+}
+
+// Close finishes the GIF, and closes the file if the writer was made by CreateGIF.
+func (g *GIFWriter) Close() error {
+	var err error
+	if g.started {
+		_, err = g.w.Write([]byte{0x3b})
+		g.started = false
+	}
+	if g.closer != nil {
+		if cerr := g.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// PNGSequence writes each frame to its own PNG file, named by formatting Pattern with the
+// frame number, for example "frames/%04d.png". Missing directories are created.
+type PNGSequence struct {
+	Pattern string
+	n       int
+}
+
+// NewPNGSequence creates a writer that numbers its files from 0.
+func NewPNGSequence(pattern string) *PNGSequence {
+	return &PNGSequence{Pattern: pattern}
+}
+
+func (s *PNGSequence) WriteFrame(img image.Image) error {
+	path := fmt.Sprintf(s.Pattern, s.n)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	s.n++
+	return savePNG(path, img)
+}
+
+func (s *PNGSequence) Close() error {
+	return nil
+}
+
+// FrameRecorder passes every nth frame it is given to a FrameWriter.
+type FrameRecorder struct {
+	Writer FrameWriter
+	// Every keeps one frame out of every Every frames. 0 and 1 keep them all.
+	Every int
+
+	frame int
+	err   error
+}
+
+// NewFrameRecorder creates a recorder that keeps one frame out of every.
+func NewFrameRecorder(w FrameWriter, every int) *FrameRecorder {
+	return &FrameRecorder{Writer: w, Every: every}
+}
+
+// Capture counts a frame and writes it if it is one to keep. Once writing fails, nothing more
+// is written and the error is returned by Close.
+func (r *FrameRecorder) Capture(img image.Image) {
+	if r.keep() {
+		r.write(img)
+	}
+}
+
+// keep counts a frame and reports whether it is one to write, so callers can skip getting
+// the image for frames that aren't.
+func (r *FrameRecorder) keep() bool {
+	frame := r.frame
+	r.frame++
+	return r.err == nil && (r.Every <= 1 || frame%r.Every == 0)
+}
+
+func (r *FrameRecorder) write(img image.Image) {
+	r.err = r.Writer.WriteFrame(img)
+}
+
+// Close finishes writing and returns the first error encountered.
+func (r *FrameRecorder) Close() error {
+	err := r.Writer.Close()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// CaptureTicks steps the game for the given number of ticks without opening a window, drawing
+// every nth tick on the CPU and writing it to w, which is closed at the end. Like RenderTicks
+// it doesn't depend on the game's Clock, so the same game always captures the same frames.
+func CaptureTicks(game *Game, ticks, every, width, height int, w FrameWriter) error {
+	// skip frames here rather than in the recorder so the ones that aren't kept aren't drawn
+	recorder := NewFrameRecorder(w, 1)
+	runner := NewRunner(game)
+	runner.AfterTick = func(tick uint64) {
+		if every <= 1 || tick%uint64(every) == 0 {
+			recorder.Capture(RasterizeSpace(game.Space, width, height, game.Theme))
+		}
+	}
+	runner.Run(ticks)
+	return recorder.Close()
+}
+
+// Capturing reports whether drawn frames are being captured.
+func (g *Game) Capturing() bool {
+	return g.frames != nil
+}
+
+// StartCapture passes every nth frame that Draw draws to w, without the debug text.
+func (g *Game) StartCapture(w FrameWriter, every int) {
+	g.frames = NewFrameRecorder(w, every)
+}
+
+// StopCapture stops capturing, closes the writer and returns the first error encountered.
+func (g *Game) StopCapture() error {
+	if g.frames == nil {
+		return nil
+	}
+	err := g.frames.Close()
+	g.frames = nil
+	return err
+}
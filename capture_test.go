@@ -0,0 +1,58 @@
+package cpebiten
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"testing"
+)
+
+func TestCaptureTicksGIF(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		ticks, every int
+		frames       int
+	}{
+		{name: "every tick", ticks: 5, every: 1, frames: 5},
+		{name: "every third tick", ticks: 12, every: 3, frames: 4},
+		{name: "single frame", ticks: 2, every: 3, frames: 1},
+		{name: "no ticks", ticks: 0, every: 1, frames: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := CaptureTicks(fallingGame(), test.ticks, test.every, 64, 48, NewGIFWriter(&buf, 5)); err != nil {
+				t.Fatal(err)
+			}
+			if test.frames == 0 {
+				if buf.Len() != 0 {
+					t.Errorf("wrote %d bytes without any frames", buf.Len())
+				}
+				return
+			}
+
+			anim, err := gif.DecodeAll(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(anim.Image) != test.frames {
+				t.Errorf("got %d frames, want %d", len(anim.Image), test.frames)
+			}
+			for i, delay := range anim.Delay {
+				if delay != 5 {
+					t.Errorf("frame %d has delay %d, want 5", i, delay)
+				}
+			}
+			for i, frame := range anim.Image {
+				if frame.Rect != image.Rect(0, 0, 64, 48) {
+					t.Errorf("frame %d is %v, want 64x48", i, frame.Rect)
+				}
+			}
+			if anim.LoopCount != 0 {
+				t.Errorf("got loop count %d, want 0 to loop forever", anim.LoopCount)
+			}
+			if anim.Config.Width != 64 || anim.Config.Height != 48 {
+				t.Errorf("got a %dx%d animation, want 64x48", anim.Config.Width, anim.Config.Height)
+			}
+		})
+	}
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
 	"github.com/jakecoffman/cpebiten"
-	"log"
 )
 
 const (
@@ -91,13 +89,8 @@ func NewGame() *cpebiten.Game {
 	circle := cpebiten.AddCircle(space, cp.Vector{screenWidth/2, screenHeight - 100}, 10, radius)
 	circle.Body().SetVelocity(0, -300)
 
-	return cpebiten.NewGame(space, 180)
-}
-
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Contact Graph")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
-	}
+	game := cpebiten.NewGame(space, 180)
+	// keep the last 5 seconds around so Backspace can rewind
+	game.History = cpebiten.NewHistory(5 * 180)
+	return game
 }
@@ -0,0 +1,53 @@
+package cpebiten
+
+import "time"
+
+// Clock is the source of time that drives the physics accumulator in Game.PhysicsTick.
+type Clock interface {
+	// Now returns the current time in seconds.
+	Now() float64
+}
+
+// RealClock reads the host's wall clock. It is the default clock used by NewGame.
+type RealClock struct{}
+
+func (RealClock) Now() float64 {
+	return float64(time.Now().UnixNano()) / 1.e9
+}
+
+// FixedClock advances by exactly Increment seconds every time Now is called, so a game
+// using it steps the same way on every run regardless of how fast the host is.
+type FixedClock struct {
+	Increment float64
+	now       float64
+}
+
+// NewFixedClock creates a clock that advances by increment seconds per call to Now.
+func NewFixedClock(increment float64) *FixedClock {
+	return &FixedClock{Increment: increment}
+}
+
+func (c *FixedClock) Now() float64 {
+	c.now += c.Increment
+	return c.now
+}
+
+// ManualClock only moves when Advance or Set is called. Useful in tests that need to
+// control exactly how much time passes between ticks.
+type ManualClock struct {
+	now float64
+}
+
+func (c *ManualClock) Now() float64 {
+	return c.now
+}
+
+// Advance moves the clock forward by dt seconds.
+func (c *ManualClock) Advance(dt float64) {
+	c.now += dt
+}
+
+// Set moves the clock to an absolute time in seconds.
+func (c *ManualClock) Set(now float64) {
+	c.now = now
+}
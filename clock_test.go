@@ -0,0 +1,80 @@
+package cpebiten
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPhysicsTickFixedClock(t *testing.T) {
+	game := fallingGame()
+	game.Clock = NewFixedClock(1. / 60)
+	for i := 0; i < 120; i++ {
+		game.PhysicsTick()
+	}
+	if game.Ticks != 120 {
+		t.Errorf("got %d ticks from 120 frames of a 60 tick per second clock, want 120", game.Ticks)
+	}
+
+	// the same scene on the same clock steps the same every time
+	again := fallingGame()
+	again.Clock = NewFixedClock(1. / 60)
+	for i := 0; i < 120; i++ {
+		again.PhysicsTick()
+	}
+	if ballY(game) != ballY(again) {
+		t.Errorf("the ball ended up at %v and then at %v", ballY(game), ballY(again))
+	}
+}
+
+func TestPhysicsTickManualClock(t *testing.T) {
+	tests := []struct {
+		name          string
+		advance       []float64
+		wantTicks     uint64
+		wantRemainder float64
+	}{
+		{"no time", []float64{0}, 0, 0},
+		{"less than a tick", []float64{0.5 / 60}, 0, 0.5 / 60},
+		{"one tick", []float64{1. / 60}, 1, 0},
+		{"remainder carries over", []float64{1.5 / 60, 1.5 / 60}, 3, 0},
+		{"leftover", []float64{2.5 / 60}, 2, 0.5 / 60},
+		// a frame takes at most a quarter of a second, so a long stall can't snowball
+		{"clamped", []float64{1}, 15, 0},
+		{"clamped with leftover", []float64{1, 0.5 / 60}, 15, 0.5 / 60},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &ManualClock{}
+			game := fallingGame()
+			game.Clock = clock
+			for _, dt := range test.advance {
+				clock.Advance(dt)
+				game.PhysicsTick()
+			}
+			if game.Ticks != test.wantTicks {
+				t.Errorf("got %d ticks, want %d", game.Ticks, test.wantTicks)
+			}
+			if math.Abs(game.Accumulator-test.wantRemainder) > 1e-9 {
+				t.Errorf("got %v left in the accumulator, want %v", game.Accumulator, test.wantRemainder)
+			}
+		})
+	}
+}
+
+func TestManualClockSet(t *testing.T) {
+	clock := &ManualClock{}
+	game := fallingGame()
+	game.Clock = clock
+
+	clock.Set(0.1)
+	game.PhysicsTick()
+	if game.Ticks != 6 {
+		t.Errorf("got %d ticks, want 6", game.Ticks)
+	}
+	// a clock that doesn't move doesn't step
+	game.PhysicsTick()
+	if game.Ticks != 6 {
+		t.Errorf("got %d ticks after no time passed, want 6", game.Ticks)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten"
+)
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	g.Game.Draw(screen)
+
+	// Sum the total impulse applied to the scale from all collision pairs in the contact graph.
+	var impulseSum cp.Vector
+	g.scale.EachArbiter(func(arbiter *cp.Arbiter) {
+		impulseSum = impulseSum.Add(arbiter.TotalImpulse())
+	})
+
+	dt := 1.0 / ebiten.CurrentTPS()
+
+	// Force is the impulse divided by the timestep.
+	force := impulseSum.Length() / dt
+
+	// Weight can be found similarly from the gravity vector.
+	gravity := g.Space.Gravity()
+	weight := gravity.Dot(impulseSum) / (gravity.LengthSq() * dt)
+
+	opts := cpebiten.NewDrawOptions(screen)
+	// Highlight and count the number of shapes the ball is touching.
+	var count int
+	g.ball.EachArbiter(func(arb *cp.Arbiter) {
+		_, other := arb.Shapes()
+		opts.DrawBB(other.BB(), cp.FColor{R: 1, A: 1})
+		count++
+	})
+	opts.Flush()
+
+	var magnitudeSum float64
+	var vectorSum cp.Vector
+	g.ball.EachArbiter(func(arb *cp.Arbiter) {
+		j := arb.TotalImpulse()
+		magnitudeSum += j.Length()
+		vectorSum = vectorSum.Add(j)
+	})
+
+	crushForce := (magnitudeSum - vectorSum.Length()) * dt
+	var crush string
+	if crushForce > 10 {
+		crush = "The ball is being crushed. (f: %.2f)"
+	} else {
+		crush = "The ball is not being crushed. (f %.2f)"
+	}
+
+	str := `Place objects on the scale to weigh them. The ball marks the shapes it's sitting on.
+Total force: %5.2f, Total weight: %5.2f. The ball is touching %d shapes
+` + crush
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf(str, force, weight, count, crushForce), 0, 100)
+}
+
+func main() {
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Contact Graph")
+	if err := ebiten.RunGame(NewGame()); err != nil {
+		log.Fatal(err)
+	}
+}
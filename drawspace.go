@@ -0,0 +1,48 @@
+package cpebiten
+
+import "github.com/jakecoffman/cp"
+
+// DrawAll draws shapes, constraints and collision points.
+const DrawAll = cp.DRAW_SHAPES | cp.DRAW_CONSTRAINTS | cp.DRAW_COLLISION_POINTS
+
+// DrawSpace is like cp.DrawSpace but honors drawer.Flags(), so callers can draw only the
+// shapes, only the constraints or only the collision points. Constraints are drawn over the
+// shapes and collision points over both.
+func DrawSpace(space *cp.Space, drawer cp.Drawer) {
+	flags := drawer.Flags()
+
+	if flags&cp.DRAW_SHAPES != 0 {
+		space.EachShape(func(shape *cp.Shape) {
+			cp.DrawShape(shape, drawer)
+		})
+	}
+
+	if flags&cp.DRAW_CONSTRAINTS != 0 {
+		space.EachConstraint(func(constraint *cp.Constraint) {
+			cp.DrawConstraint(constraint, drawer)
+		})
+	}
+
+	if flags&cp.DRAW_COLLISION_POINTS != 0 {
+		color := drawer.CollisionPointColor()
+		data := drawer.Data()
+
+		// every arbiter is reachable from both of its bodies, so only draw it once
+		seen := map[*cp.Arbiter]bool{}
+		space.EachBody(func(body *cp.Body) {
+			body.EachArbiter(func(arb *cp.Arbiter) {
+				if seen[arb] {
+					return
+				}
+				seen[arb] = true
+
+				set := arb.ContactPointSet()
+				for i := 0; i < set.Count; i++ {
+					a := set.Points[i].PointA.Add(set.Normal.Mult(-2))
+					b := set.Points[i].PointB.Add(set.Normal.Mult(2))
+					drawer.DrawSegment(a, b, color, data)
+				}
+			})
+		})
+	}
+}
@@ -1,16 +1,9 @@
 package cpebiten
 
 import (
-	"fmt"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"image"
+
 	"github.com/jakecoffman/cp"
-	"log"
-	"math"
-	"os"
-	"runtime/pprof"
-	"time"
 )
 
 // Game is provided as a convenience for the examples since they all share similar logic.
@@ -24,11 +17,44 @@ type Game struct {
 
 	// Accumulator shows the remaining time from the physics tick.
 	Accumulator float64
-	lastTime float64
+	lastTime    float64
+
+	// Clock drives the accumulator. Swap in a FixedClock or ManualClock to make stepping
+	// independent of the host's wall clock.
+	Clock Clock
+
+	// Ticks is the number of fixed physics steps taken so far.
+	Ticks uint64
+
+	// DrawFlags chooses what Draw shows, as a combination of cp.DRAW_SHAPES, cp.DRAW_CONSTRAINTS
+	// and cp.DRAW_COLLISION_POINTS.
+	DrawFlags uint
+
+	// Theme picks the colors Draw uses.
+	Theme Theme
+
+	// Interpolate makes Draw blend bodies between the last two physics ticks using the
+	// Accumulator, which smooths motion when TicksPerSecond doesn't match the refresh rate.
+	Interpolate bool
+	previous    map[*cp.Body]bodyTransform
+
+	// Paused stops the clock from advancing the simulation. StepOnce still works while paused.
+	Paused bool
 
-	mouseBody  *cp.Body
-	mouseJoint *cp.Constraint
-	touches    map[ebiten.TouchID]*touchInfo
+	// TimeScale multiplies the elapsed time fed to the accumulator. Below 1 is slow motion.
+	TimeScale    float64
+	pendingSteps int
+
+	// History records a snapshot of the space after every tick, and of where it started, when
+	// set, which lets Rewind scrub backwards through the last few seconds of simulation.
+	History *History
+
+	// input, and the bodies it drags things around with
+	inputState
+
+	// frames captures what Draw draws while it's set, read into pixels
+	frames *FrameRecorder
+	pixels *image.RGBA
 
 	// FixedUpdate is an optional callback that is called when a fixed update occurs.
 	FixedUpdate func()
@@ -39,125 +65,107 @@ func NewGame(space *cp.Space, ticksPerSecond float64) *Game {
 	return &Game{
 		Space:          space,
 		TicksPerSecond: ticksPerSecond,
-		mouseBody:      cp.NewKinematicBody(),
-		touches:        map[ebiten.TouchID]*touchInfo{},
-		FixedUpdate: func() {},
+		inputState:     newInputState(),
+		Clock:          RealClock{},
+		TimeScale:      1,
+		DrawFlags:      DrawAll,
+		Theme:          HashTheme,
+		FixedUpdate:    func() {},
 	}
 }
 
-func (g *Game) Update() error {
-	if inpututil.IsKeyJustPressed(ebiten.KeyF10) {
-		os.Exit(0)
-	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
-		if !profiling {
-			f, err := os.Create("profile")
-			if err != nil {
-				log.Fatal(err)
-			}
-			profile = f
-			if err := pprof.StartCPUProfile(profile); err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			pprof.StopCPUProfile()
-			profile.Close()
-		}
-		profiling = !profiling
+// PhysicsTick reads the Clock and takes as many fixed steps as the elapsed time allows. At
+// most a quarter of a second is taken from the clock per call so that a stall doesn't make
+// the game fall further and further behind, which means a ManualClock advanced by a whole
+// second only steps 15 ticks at 60 ticks per second.
+func (g *Game) PhysicsTick() {
+	newTime := g.Clock.Now()
+	frameTime := newTime - g.lastTime
+	const maxUpdate = .25
+	if frameTime > maxUpdate {
+		frameTime = maxUpdate
 	}
+	g.lastTime = newTime
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
-		ebiten.SetVsyncEnabled(vsync)
-		vsync = !vsync
+	// a replay steps the ticks between its frames in pollInput
+	if g.Replaying() {
+		return
 	}
 
-	// web stuff
-	for _, id := range inpututil.JustPressedTouchIDs() {
-		x, y := ebiten.TouchPosition(id)
-		touchPos := cp.Vector{float64(x), float64(y)}
-
-		body := cp.NewKinematicBody()
-		body.SetPosition(touchPos)
-		touch := &touchInfo{
-			id:    id,
-			body:  body,
-			joint: handleGrab(g.Space, touchPos, body),
-		}
-		g.touches[id] = touch
-	}
-	for id, touch := range g.touches {
-		if touch.joint != nil && inpututil.IsTouchJustReleased(id) {
-			g.Space.RemoveConstraint(touch.joint)
-			touch.joint = nil
-			delete(g.touches, id)
-		} else {
-			x, y := ebiten.TouchPosition(id)
-			touchPos := cp.Vector{float64(x), float64(y)}
-			// calculate velocity so the object goes as fast as the touch moved
-			newPoint := touch.body.Position().Lerp(touchPos, 0.25)
-			touch.body.SetVelocityVector(newPoint.Sub(touch.body.Position()).Mult(60.0))
-			touch.body.SetPosition(newPoint)
+	if g.Paused {
+		for ; g.pendingSteps > 0; g.pendingSteps-- {
+			g.Step()
 		}
+		return
 	}
 
-	// mouse stuff
-	x, y := ebiten.CursorPosition()
-	if x >= 0 && y >= 0 { // fixes weird mouse stuff on mac
-		mouse := cp.Vector{float64(x), float64(y)}
+	g.Accumulator += frameTime * g.TimeScale
 
-		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-			g.mouseJoint = handleGrab(g.Space, mouse, g.mouseBody)
-		}
-		if g.mouseJoint != nil && inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-			g.Space.RemoveConstraint(g.mouseJoint)
-			g.mouseJoint = nil
-		}
-		// calculate velocity so the object goes as fast as the mouse moved
-		newPoint := g.mouseBody.Position().Lerp(mouse, 0.25)
-		g.mouseBody.SetVelocityVector(newPoint.Sub(g.mouseBody.Position()).Mult(60.0))
-		g.mouseBody.SetPosition(newPoint)
+	// the time between two readings of a clock is rarely exactly dt even when it ticks by
+	// dt, so allow for rounding or a FixedClock would drop a tick every so often
+	const epsilon = 1e-9
+	dt := 1. / g.TicksPerSecond
+	for g.Accumulator >= dt-epsilon {
+		g.Step()
+		g.Accumulator -= dt
 	}
+}
 
-	g.PhysicsTick()
-
-	return nil
+// Pause freezes the simulation until Resume is called.
+func (g *Game) Pause() {
+	g.Paused = true
 }
 
-func (g *Game) PhysicsTick() {
-	newTime := float64(time.Now().UnixNano()) / 1.e9
-	frameTime := newTime - g.lastTime
-	const maxUpdate = .25
-	if frameTime > maxUpdate {
-		frameTime = maxUpdate
-	}
-	g.lastTime = newTime
-	g.Accumulator += frameTime
+// Resume continues a paused simulation.
+func (g *Game) Resume() {
+	g.Paused = false
+}
 
-	//if !do {
-	//	return
-	//}
+// StepOnce pauses the simulation and queues a single tick to run on the next PhysicsTick.
+func (g *Game) StepOnce() {
+	g.Paused = true
+	g.pendingSteps++
+}
 
-	dt := 1. / g.TicksPerSecond
-	for g.Accumulator >= dt {
-		g.FixedUpdate()
-		g.Space.Step(dt)
-		g.Accumulator -= dt
+// Step advances the simulation by exactly one fixed tick, ignoring the Clock.
+func (g *Game) Step() {
+	if g.History != nil {
+		// record the state being stepped from if the history doesn't have it, such as the
+		// starting state on the first tick, so Rewind can go all the way back to it
+		if latest := g.History.Latest(); latest == nil || latest.Tick != g.Ticks {
+			g.History.Push(TakeSnapshot(g.Space, g.Ticks, g.grabBodies()...))
+		}
+	}
+	if g.Interpolate {
+		g.recordTransforms()
+	}
+	g.FixedUpdate()
+	g.Space.Step(1. / g.TicksPerSecond)
+	g.Ticks++
+	if g.History != nil {
+		g.History.Push(TakeSnapshot(g.Space, g.Ticks, g.grabBodies()...))
 	}
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	g.PhysicsTick()
-
-	opts := NewDrawOptions(screen)
-	cp.DrawSpace(g.Space, opts)
-	opts.Flush()
-
-	out := fmt.Sprintf("FPS: %0.2f", ebiten.CurrentFPS())
-	if profiling {
-		out += "\nprofiling"
+// Rewind pauses the game and restores the space to how it was the given number of ticks ago.
+// It returns false if History is not set or doesn't reach back that far, and while input is
+// being recorded or replayed, since a recording can only go forwards.
+func (g *Game) Rewind(ticks uint64) bool {
+	if g.History == nil || ticks > g.Ticks || g.Recording() || g.Replaying() {
+		return false
+	}
+	snapshot, ok := g.History.Get(g.Ticks - ticks)
+	if !ok {
+		return false
 	}
-	ebitenutil.DebugPrint(screen, out)
+
+	g.releaseGrabs()
+	snapshot.Restore(g.Space, g.grabBodies()...)
+	g.Ticks = snapshot.Tick
+	g.Accumulator = 0
+	g.previous = nil
+	g.Paused = true
+	return true
 }
 
 const (
@@ -165,10 +173,6 @@ const (
 	ScreenWidth  = 600
 )
 
-func (g *Game) Layout(int, int) (int, int) {
-	return ScreenWidth, ScreenHeight
-}
-
 var GrabbableMaskBit uint = 1 << 31
 
 var Grabbable = cp.ShapeFilter{
@@ -177,37 +181,3 @@ var Grabbable = cp.ShapeFilter{
 var NotGrabbable = cp.ShapeFilter{
 	cp.NO_GROUP, ^GrabbableMaskBit, ^GrabbableMaskBit,
 }
-
-func handleGrab(space *cp.Space, pos cp.Vector, touchBody *cp.Body) *cp.Constraint {
-	const radius = 5.0 // make it easier to grab stuff
-	info := space.PointQueryNearest(pos, radius, Grabbable)
-
-	// avoid infinite mass objects
-	if info.Shape != nil && info.Shape.Body().Mass() < math.MaxFloat64 {
-		var nearest cp.Vector
-		if info.Distance > 0 {
-			nearest = info.Point
-		} else {
-			nearest = pos
-		}
-
-		// create a joint between the invisible mouse body and the shape
-		body := info.Shape.Body()
-		joint := cp.NewPivotJoint2(touchBody, body, cp.Vector{}, body.WorldToLocal(nearest))
-		joint.SetMaxForce(50000)
-		joint.SetErrorBias(math.Pow(1.0-0.15, 60.0))
-		space.AddConstraint(joint)
-		return joint
-	}
-
-	return nil
-}
-
-type touchInfo struct {
-	id    ebiten.TouchID
-	body  *cp.Body
-	joint *cp.Constraint
-}
-
-var profiling, vsync bool
-var profile *os.File
@@ -0,0 +1,203 @@
+//go:build !headless
+
+package cpebiten
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/jakecoffman/cp"
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"runtime/pprof"
+)
+
+func (g *Game) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF10) {
+		os.Exit(0)
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		if !profiling {
+			f, err := os.Create("profile")
+			if err != nil {
+				log.Fatal(err)
+			}
+			profile = f
+			if err := pprof.StartCPUProfile(profile); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			pprof.StopCPUProfile()
+			profile.Close()
+		}
+		profiling = !profiling
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		if !g.Capturing() {
+			// every other frame at 60 FPS
+			w, err := CreateGIF("capture.gif", 3)
+			if err != nil {
+				log.Fatal(err)
+			}
+			g.StartCapture(w, 2)
+		} else if err := g.StopCapture(); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		ebiten.SetVsyncEnabled(vsync)
+		vsync = !vsync
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		// cycle through everything, then shapes, constraints and collision points on their own
+		switch g.DrawFlags {
+		case DrawAll:
+			g.DrawFlags = cp.DRAW_SHAPES
+		case cp.DRAW_SHAPES:
+			g.DrawFlags = cp.DRAW_CONSTRAINTS
+		case cp.DRAW_CONSTRAINTS:
+			g.DrawFlags = cp.DRAW_COLLISION_POINTS
+		default:
+			g.DrawFlags = DrawAll
+		}
+	}
+
+	// a replay drives the ticks, so the time controls would only get it out of step
+	if !g.Replaying() {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+			g.Paused = !g.Paused
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF6) {
+			g.StepOnce()
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF7) {
+			g.TimeScale = math.Max(g.TimeScale/2, 1./16)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+			g.TimeScale = math.Min(g.TimeScale*2, 4)
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF2) {
+		if err := SaveSpaceFile("space.json", g.Space); err != nil {
+			log.Println(err)
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF4) {
+		svg := NewSVG(ScreenWidth, ScreenHeight)
+		svg.SetFlags(g.DrawFlags)
+		svg.SetTheme(g.Theme)
+		svg.SetBackground(cp.FColor{A: 1})
+		DrawSpace(g.Space, svg)
+		if err := svg.SaveFile("space.svg"); err != nil {
+			log.Println(err)
+		}
+	}
+
+	// Rewind does nothing while recording or replaying, where it would get the ticks out of step
+	if ebiten.IsKeyPressed(ebiten.KeyBackspace) {
+		g.Rewind(1)
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) && !g.Replaying() {
+		if !g.Recording() {
+			f, err := os.Create("input.jsonl")
+			if err != nil {
+				log.Fatal(err)
+			}
+			recordFile = f
+			if err := g.StartRecording(recordFile); err != nil {
+				log.Println(err)
+				recordFile.Close()
+			}
+		} else {
+			if err := g.StopRecording(); err != nil {
+				log.Println(err)
+			}
+			recordFile.Close()
+		}
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) && !g.Recording() {
+		if err := g.ReplayFile("input.jsonl"); err != nil {
+			log.Println(err)
+		}
+	}
+
+	g.update(captureFrame)
+	return nil
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	opts := NewDrawOptions(screen)
+	opts.SetFlags(g.DrawFlags)
+	opts.SetTheme(g.Theme)
+	if g.Interpolate {
+		g.DrawSpaceInterpolated(opts)
+	} else {
+		DrawSpace(g.Space, opts)
+	}
+	opts.Flush()
+
+	if g.frames != nil && g.frames.keep() {
+		g.pixels = readPixels(screen, g.pixels)
+		g.frames.write(g.pixels)
+	}
+
+	out := fmt.Sprintf("FPS: %0.2f", ebiten.CurrentFPS())
+	if profiling {
+		out += "\nprofiling"
+	}
+	if g.Paused {
+		out += fmt.Sprintf("\npaused (tick %d)", g.Ticks)
+	}
+	if g.Capturing() {
+		out += "\ncapturing frames"
+	}
+	if g.Recording() {
+		out += "\nrecording input"
+	}
+	if g.Replaying() {
+		out += "\nreplaying input"
+	}
+	if g.TimeScale != 1 {
+		out += fmt.Sprintf("\ntime scale %gx", g.TimeScale)
+	}
+	ebitenutil.DebugPrint(screen, out)
+}
+
+// readPixels copies img into dst, or into a new image if dst is nil or a different size, and
+// returns it. This version of Ebiten has no ReadPixels, but the first At reads the whole image
+// back from the GPU and the rest are served from that copy, so this stays cheap as long as
+// the pixels are copied straight into dst rather than converted one color.Color at a time.
+func readPixels(img *ebiten.Image, dst *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	if dst == nil || dst.Rect != bounds {
+		dst = image.NewRGBA(bounds)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := dst.Pix[dst.PixOffset(bounds.Min.X, y):]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.At(x, y).(color.RGBA)
+			i := 4 * (x - bounds.Min.X)
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return dst
+}
+
+func (g *Game) Layout(int, int) (int, int) {
+	return ScreenWidth, ScreenHeight
+}
+
+var profiling, vsync bool
+var profile, recordFile *os.File
@@ -0,0 +1,76 @@
+package cpebiten
+
+import "testing"
+
+func TestPause(t *testing.T) {
+	clock := &ManualClock{}
+	game := fallingGame()
+	game.Clock = clock
+
+	game.Pause()
+	clock.Advance(0.1)
+	game.PhysicsTick()
+	if game.Ticks != 0 {
+		t.Errorf("got %d ticks while paused, want 0", game.Ticks)
+	}
+
+	// time that passed while paused is not made up for after resuming
+	game.Resume()
+	clock.Advance(1. / 60)
+	game.PhysicsTick()
+	if game.Ticks != 1 {
+		t.Errorf("got %d ticks after resuming, want 1", game.Ticks)
+	}
+}
+
+func TestStepOnce(t *testing.T) {
+	clock := &ManualClock{}
+	game := fallingGame()
+	game.Clock = clock
+
+	game.StepOnce()
+	game.StepOnce()
+	if !game.Paused {
+		t.Error("StepOnce didn't pause the game")
+	}
+	if game.Ticks != 0 {
+		t.Errorf("got %d ticks before PhysicsTick, want 0", game.Ticks)
+	}
+
+	clock.Advance(1)
+	game.PhysicsTick()
+	if game.Ticks != 2 {
+		t.Errorf("got %d ticks from two steps, want 2", game.Ticks)
+	}
+	game.PhysicsTick()
+	if game.Ticks != 2 {
+		t.Errorf("got %d ticks after the steps ran out, want 2", game.Ticks)
+	}
+}
+
+func TestTimeScale(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeScale float64
+		want      uint64
+	}{
+		{"normal", 1, 12},
+		{"slow motion", 0.5, 6},
+		{"sixteenth", 1. / 16, 0},
+		{"fast", 2, 24},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &ManualClock{}
+			game := fallingGame()
+			game.Clock = clock
+			game.TimeScale = test.timeScale
+			clock.Advance(0.2)
+			game.PhysicsTick()
+			if game.Ticks != test.want {
+				t.Errorf("got %d ticks, want %d", game.Ticks, test.want)
+			}
+		})
+	}
+}
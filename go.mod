@@ -1,14 +1,42 @@
 module github.com/jakecoffman/cpebiten
 
-go 1.15
+go 1.22
 
 require (
-	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20201108214237-06ea97f0c265 // indirect
 	github.com/hajimehoshi/ebiten/v2 v2.1.0-alpha.4.0.20201215174106-e856b236f30e
 	github.com/jakecoffman/cp v1.0.0
-	golang.org/x/exp v0.0.0-20201215153530-b5a6e247da10 // indirect
+	github.com/klauspost/compress v1.18.0
 	golang.org/x/image v0.10.0
+)
+
+require (
+	dmitri.shuralyov.com/gpu/mtl v0.0.0-20190408044501-666a987793e9 // indirect
+	github.com/BurntSushi/xgb v0.0.0-20160522181843-27f122750802 // indirect
+	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20201108214237-06ea97f0c265 // indirect
+	github.com/gofrs/flock v0.8.0 // indirect
+	github.com/hajimehoshi/bitmapfont/v2 v2.1.2 // indirect
+	github.com/hajimehoshi/file2byteslice v0.0.0-20200812174855-0e5e8a80490e // indirect
+	github.com/hajimehoshi/go-mp3 v0.3.1 // indirect
+	github.com/hajimehoshi/oto v0.6.8 // indirect
+	github.com/jfreymuth/oggvorbis v1.0.1 // indirect
+	github.com/jfreymuth/vorbis v1.0.0 // indirect
+	github.com/kr/pty v1.1.1 // indirect
+	github.com/kr/text v0.1.0 // indirect
+	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4 // indirect
+	github.com/yuin/goldmark v1.4.13 // indirect
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
+	golang.org/x/exp v0.0.0-20201215153530-b5a6e247da10 // indirect
 	golang.org/x/mobile v0.0.0-20201208152944-da85bec010a2 // indirect
+	golang.org/x/mod v0.8.0 // indirect
+	golang.org/x/net v0.6.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/term v0.5.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
+	golang.org/x/tools v0.6.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	gopkg.in/check.v1 v1.0.0-20200902074654-038fdea0a05b // indirect
 )
 
 //replace (
@@ -0,0 +1,111 @@
+package cpebiten
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tolerance is how different a rendering may be from its golden image and still match. Small
+// differences are expected between platforms since floating point results aren't bit for bit
+// identical everywhere, and a simulation amplifies them over time.
+type Tolerance struct {
+	// Channel is how far apart two color channels can be, out of 255, and count as equal.
+	Channel uint8
+	// Pixels is the fraction of pixels allowed to differ by more than Channel.
+	Pixels float64
+}
+
+// DefaultTolerance ignores a little anti-aliasing noise and a handful of stray pixels.
+var DefaultTolerance = Tolerance{Channel: 16, Pixels: 0.002}
+
+// UpdateGoldenEnv is the environment variable that makes CheckGolden write new golden images
+// instead of comparing against them, e.g. UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// RenderTicks steps the game's physics for the given number of ticks without opening a window,
+// then draws the space on the CPU. The ticks don't depend on the game's Clock, so the same game
+// always renders the same.
+func RenderTicks(game *Game, ticks, width, height int) *image.RGBA {
+	NewRunner(game).Run(ticks)
+	return RasterizeSpace(game.Space, width, height, game.Theme)
+}
+
+// CheckGolden compares img with the golden PNG at path. When they don't match within the
+// tolerance, img is written next to it with an .actual.png suffix so the two can be compared
+// by eye. If UpdateGoldenEnv is set, img is saved as the new golden image instead.
+func CheckGolden(path string, img image.Image, tolerance Tolerance) error {
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return savePNG(path, img)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%v (set %s=1 to create it)", err, UpdateGoldenEnv)
+	}
+	golden, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("decoding %s: %w", path, err)
+	}
+
+	if golden.Bounds().Size() != img.Bounds().Size() {
+		return fmt.Errorf("%s is %v but the rendering is %v", path, golden.Bounds().Size(), img.Bounds().Size())
+	}
+
+	bad := DiffImages(golden, img, tolerance.Channel)
+	total := img.Bounds().Dx() * img.Bounds().Dy()
+	if float64(bad) <= tolerance.Pixels*float64(total) {
+		return nil
+	}
+
+	actual := strings.TrimSuffix(path, ".png") + ".actual.png"
+	if err := savePNG(actual, img); err != nil {
+		return err
+	}
+	return fmt.Errorf("%d of %d pixels differ from %s, see %s", bad, total, path, actual)
+}
+
+// DiffImages counts the pixels where any channel of a and b differs by more than channel.
+// The images must be the same size.
+func DiffImages(a, b image.Image, channel uint8) int {
+	ab, bb := a.Bounds(), b.Bounds()
+	limit := uint32(channel) * 0x101
+
+	var bad int
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			r1, g1, b1, a1 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			if absDiff(r1, r2) > limit || absDiff(g1, g2) > limit || absDiff(b1, b2) > limit || absDiff(a1, a2) > limit {
+				bad++
+			}
+		}
+	}
+	return bad
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func savePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
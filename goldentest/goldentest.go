@@ -0,0 +1,55 @@
+// Package goldentest renders scenes in tests and compares them with golden images.
+package goldentest
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/jakecoffman/cpebiten"
+)
+
+// Test is a scene to render with cpebiten.RenderTicks and compare with a golden image.
+type Test struct {
+	// Name names the subtest and the golden image, which is testdata/<Name>.png.
+	Name string
+	// Scene creates the game to render. It is given a random source with the same seed every
+	// time, so scenes that take their randomness from it render the same every time.
+	Scene func(rng *rand.Rand) *cpebiten.Game
+	// Ticks is how many ticks to step before rendering.
+	Ticks int
+	// Width and Height are the size of the rendering, cpebiten.ScreenWidth by
+	// cpebiten.ScreenHeight if zero.
+	Width, Height int
+	// Tolerance is how far the rendering can be from the golden image,
+	// cpebiten.DefaultTolerance if nil.
+	Tolerance *cpebiten.Tolerance
+}
+
+// Run renders each scene in a subtest and fails it if the rendering doesn't match its golden
+// image, as checked by cpebiten.CheckGolden.
+func Run(t *testing.T, tests []Test) {
+	t.Helper()
+	for _, test := range tests {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			width, height := test.Width, test.Height
+			if width == 0 {
+				width = cpebiten.ScreenWidth
+			}
+			if height == 0 {
+				height = cpebiten.ScreenHeight
+			}
+			tolerance := cpebiten.DefaultTolerance
+			if test.Tolerance != nil {
+				tolerance = *test.Tolerance
+			}
+
+			img := cpebiten.RenderTicks(test.Scene(rand.New(rand.NewSource(1))), test.Ticks, width, height)
+			path := filepath.Join("testdata", test.Name+".png")
+			if err := cpebiten.CheckGolden(path, img, tolerance); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+package cpebiten
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+
+	"github.com/jakecoffman/cp"
+)
+
+// MouseButton, Key and TouchID have the same values as Ebiten's types of the same name, so an
+// InputFrame can be read and replayed without Ebiten.
+type (
+	MouseButton int
+	Key         int
+	TouchID     int
+)
+
+const mouseButtonLeft MouseButton = 0
+
+// InputFrame is the state of the mouse, touches and keyboard for one call to Game.Update,
+// tagged with the physics tick it was applied to. Edges such as "just pressed" are derived
+// by comparing a frame with the one before it, so a log of frames is enough to replay a
+// session exactly.
+type InputFrame struct {
+	Tick    uint64        `json:"tick"`
+	CursorX int           `json:"x"`
+	CursorY int           `json:"y"`
+	Buttons []MouseButton `json:"buttons,omitempty"`
+	Keys    []Key         `json:"keys,omitempty"`
+	Touches []TouchFrame  `json:"touches,omitempty"`
+}
+
+// TouchFrame is the position of a single active touch.
+type TouchFrame struct {
+	ID TouchID `json:"id"`
+	X  int     `json:"x"`
+	Y  int     `json:"y"`
+}
+
+func (f *InputFrame) button(button MouseButton) bool {
+	for _, b := range f.Buttons {
+		if b == button {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *InputFrame) key(key Key) bool {
+	for _, k := range f.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *InputFrame) touch(id TouchID) (TouchFrame, bool) {
+	for _, t := range f.Touches {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return TouchFrame{}, false
+}
+
+// Input answers questions about the input of the current frame. Game.Input returns one so
+// that code reading input through it is recorded and replayed along with the game.
+type Input struct {
+	prev, cur *InputFrame
+}
+
+func (in Input) CursorPosition() (int, int) {
+	return in.cur.CursorX, in.cur.CursorY
+}
+
+func (in Input) mouseJustPressed(button MouseButton) bool {
+	return in.cur.button(button) && !in.prev.button(button)
+}
+
+func (in Input) mouseJustReleased(button MouseButton) bool {
+	return !in.cur.button(button) && in.prev.button(button)
+}
+
+func (in Input) justPressedTouches() []TouchID {
+	var ids []TouchID
+	for _, t := range in.cur.Touches {
+		if _, ok := in.prev.touch(t.ID); !ok {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+func (in Input) touchJustReleased(id TouchID) bool {
+	_, now := in.cur.touch(id)
+	_, before := in.prev.touch(id)
+	return before && !now
+}
+
+func (in Input) touchPosition(id TouchID) (int, int) {
+	if t, ok := in.cur.touch(id); ok {
+		return t.X, t.Y
+	}
+	t, _ := in.prev.touch(id)
+	return t.X, t.Y
+}
+
+// Input returns the input for the current frame.
+func (g *Game) Input() Input {
+	if g.frame == nil {
+		return Input{&InputFrame{}, &InputFrame{}}
+	}
+	return Input{g.prevFrame, g.frame}
+}
+
+// inputState is the part of Game that reads input and drags bodies around with it.
+type inputState struct {
+	// input of the current and previous frame, plus state for recording and replaying it
+	frame, prevFrame *InputFrame
+	polled           bool
+	recording        *json.Encoder
+	recordWriter     *bufio.Writer
+	recordErr        error
+	replay           []*InputFrame
+
+	mouseBody  *cp.Body
+	mouseJoint *cp.Constraint
+	touches    map[TouchID]*touchInfo
+}
+
+func newInputState() inputState {
+	return inputState{
+		mouseBody: cp.NewKinematicBody(),
+		touches:   map[TouchID]*touchInfo{},
+	}
+}
+
+type touchInfo struct {
+	id    TouchID
+	body  *cp.Body
+	joint *cp.Constraint
+}
+
+// UpdateInput runs a frame of the game with the given input in place of Ebiten's: it grabs
+// and drags bodies with the mouse and touches and then steps the physics, like Update. It
+// lets a game be driven without a window, and live is recorded like input read from Ebiten.
+// A replay, while there is one, takes the place of live. live's Tick is set by UpdateInput.
+func (g *Game) UpdateInput(live *InputFrame) {
+	g.update(func() *InputFrame {
+		return live
+	})
+}
+
+func (g *Game) update(live func() *InputFrame) {
+	g.pollInput(live)
+	g.applyInput()
+	g.PhysicsTick()
+	g.polled = false
+}
+
+// pollInput captures this frame's input, from live or from a replay. Calling it more than
+// once per frame has no effect.
+func (g *Game) pollInput(live func() *InputFrame) {
+	if g.polled {
+		return
+	}
+	g.polled = true
+
+	if g.prevFrame = g.frame; g.prevFrame == nil {
+		g.prevFrame = &InputFrame{}
+	}
+
+	if g.replay != nil {
+		if len(g.replay) == 0 {
+			g.replay = nil
+		} else {
+			next := g.replay[0]
+			g.replay = g.replay[1:]
+			// run the ticks that happened between the recorded frames with the old input
+			for g.Ticks < next.Tick {
+				g.Step()
+			}
+			g.frame = next
+			return
+		}
+	}
+
+	g.frame = live()
+	g.frame.Tick = g.Ticks
+	if g.recording != nil {
+		if err := g.recording.Encode(g.frame); err != nil {
+			g.recordErr = err
+			g.stopRecording()
+		}
+	}
+}
+
+// applyInput grabs bodies with the mouse and touches that were just pressed, drags the
+// grabbed bodies along and lets go of them when released.
+func (g *Game) applyInput() {
+	input := g.Input()
+
+	// web stuff
+	for _, id := range input.justPressedTouches() {
+		x, y := input.touchPosition(id)
+		touchPos := cp.Vector{float64(x), float64(y)}
+
+		body := cp.NewKinematicBody()
+		body.SetPosition(touchPos)
+		touch := &touchInfo{
+			id:    id,
+			body:  body,
+			joint: handleGrab(g.Space, touchPos, body),
+		}
+		g.touches[id] = touch
+	}
+	for id, touch := range g.touches {
+		if touch.joint != nil && input.touchJustReleased(id) {
+			g.Space.RemoveConstraint(touch.joint)
+			touch.joint = nil
+			delete(g.touches, id)
+		} else {
+			x, y := input.touchPosition(id)
+			touchPos := cp.Vector{float64(x), float64(y)}
+			// calculate velocity so the object goes as fast as the touch moved
+			newPoint := touch.body.Position().Lerp(touchPos, 0.25)
+			touch.body.SetVelocityVector(newPoint.Sub(touch.body.Position()).Mult(60.0))
+			touch.body.SetPosition(newPoint)
+		}
+	}
+
+	// mouse stuff
+	x, y := input.CursorPosition()
+	if x >= 0 && y >= 0 { // fixes weird mouse stuff on mac
+		mouse := cp.Vector{float64(x), float64(y)}
+
+		if input.mouseJustPressed(mouseButtonLeft) {
+			g.mouseJoint = handleGrab(g.Space, mouse, g.mouseBody)
+		}
+		if g.mouseJoint != nil && input.mouseJustReleased(mouseButtonLeft) {
+			g.Space.RemoveConstraint(g.mouseJoint)
+			g.mouseJoint = nil
+		}
+		// calculate velocity so the object goes as fast as the mouse moved
+		newPoint := g.mouseBody.Position().Lerp(mouse, 0.25)
+		g.mouseBody.SetVelocityVector(newPoint.Sub(g.mouseBody.Position()).Mult(60.0))
+		g.mouseBody.SetPosition(newPoint)
+	}
+}
+
+// grabBodies are the bodies outside of the space that the mouse and touches drag things with.
+func (g *Game) grabBodies() []*cp.Body {
+	bodies := []*cp.Body{g.mouseBody}
+	for _, touch := range g.touches {
+		bodies = append(bodies, touch.body)
+	}
+	return bodies
+}
+
+func (g *Game) releaseGrabs() {
+	if g.mouseJoint != nil {
+		g.Space.RemoveConstraint(g.mouseJoint)
+		g.mouseJoint = nil
+	}
+	for id, touch := range g.touches {
+		if touch.joint != nil {
+			g.Space.RemoveConstraint(touch.joint)
+		}
+		delete(g.touches, id)
+	}
+}
+
+func handleGrab(space *cp.Space, pos cp.Vector, touchBody *cp.Body) *cp.Constraint {
+	const radius = 5.0 // make it easier to grab stuff
+	info := space.PointQueryNearest(pos, radius, Grabbable)
+
+	// avoid infinite mass objects
+	if info.Shape != nil && info.Shape.Body().Mass() < math.MaxFloat64 {
+		var nearest cp.Vector
+		if info.Distance > 0 {
+			nearest = info.Point
+		} else {
+			nearest = pos
+		}
+
+		// create a joint between the invisible mouse body and the shape
+		body := info.Shape.Body()
+		joint := cp.NewPivotJoint2(touchBody, body, cp.Vector{}, body.WorldToLocal(nearest))
+		joint.SetMaxForce(50000)
+		joint.SetErrorBias(math.Pow(1.0-0.15, 60.0))
+		space.AddConstraint(joint)
+		return joint
+	}
+
+	return nil
+}
+
+// recordingStart is the first line of a recording. It holds the state of the game when
+// recording started, so that a recording started partway through a session can be replayed.
+type recordingStart struct {
+	Tick  uint64      `json:"tick"`
+	Mouse cp.Vector   `json:"mouse"`
+	Input *InputFrame `json:"input"`
+	Space *SpaceJSON  `json:"space"`
+}
+
+// Replaying reports whether the game is being driven by a replay.
+func (g *Game) Replaying() bool {
+	return g.replay != nil
+}
+
+// Recording reports whether input is being recorded.
+func (g *Game) Recording() bool {
+	return g.recording != nil
+}
+
+// StartRecording writes the state of the space and every subsequent input frame to w as
+// JSON, one value per line. Anything being dragged is let go of first, since a grab can't be
+// recorded halfway through, and what the space carries over between steps is reset the way
+// Replay resets it. State the game keeps outside of the space, such as in the examples'
+// package variables, isn't recorded.
+func (g *Game) StartRecording(w io.Writer) error {
+	space, err := MarshalSpace(g.Space)
+	if err != nil {
+		return err
+	}
+	g.releaseGrabs()
+	// the replay starts without cp's caches, so drop them here too
+	resetWarmStart(g.Space)
+	g.recordWriter = bufio.NewWriter(w)
+	g.recording = json.NewEncoder(g.recordWriter)
+	g.recordErr = nil
+
+	start := recordingStart{Tick: g.Ticks, Mouse: g.mouseBody.Position(), Input: g.frame, Space: space}
+	if err := g.recording.Encode(start); err != nil {
+		g.recordErr = err
+		g.stopRecording()
+		return err
+	}
+	return nil
+}
+
+// StopRecording stops recording input and returns the first error encountered while writing.
+func (g *Game) StopRecording() error {
+	g.stopRecording()
+	return g.recordErr
+}
+
+func (g *Game) stopRecording() {
+	if g.recordWriter != nil {
+		if err := g.recordWriter.Flush(); err != nil && g.recordErr == nil {
+			g.recordErr = err
+		}
+	}
+	g.recording = nil
+	g.recordWriter = nil
+}
+
+// Replay puts the game back in the state the recording read from r started in and feeds it
+// the recorded frames in place of live input. The game must be running the scene the
+// recording was made with, though not necessarily from the start. Live input resumes once
+// the replay runs out.
+func (g *Game) Replay(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	var start recordingStart
+	if err := dec.Decode(&start); err != nil {
+		return err
+	}
+	if start.Space == nil {
+		return errors.New("recording doesn't start with the state of the space")
+	}
+
+	var frames []*InputFrame
+	for {
+		frame := &InputFrame{}
+		if err := dec.Decode(frame); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		frames = append(frames, frame)
+	}
+
+	g.releaseGrabs()
+	if err := restoreSpace(g.Space, start.Space); err != nil {
+		return err
+	}
+	resetWarmStart(g.Space)
+	g.mouseBody.SetPosition(start.Mouse)
+	g.frame = start.Input
+	g.Ticks = start.Tick
+	g.Accumulator = 0
+	g.previous = nil
+	if g.History != nil {
+		g.History.Clear()
+	}
+	g.replay = frames
+	return nil
+}
+
+// ReplayFile is a convenience for Replay that reads the recording from a file.
+func (g *Game) ReplayFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return g.Replay(f)
+}
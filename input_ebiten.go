@@ -0,0 +1,69 @@
+//go:build !headless
+
+package cpebiten
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// captureFrame reads the live Ebiten input state.
+func captureFrame() *InputFrame {
+	frame := &InputFrame{}
+	frame.CursorX, frame.CursorY = ebiten.CursorPosition()
+	for b := ebiten.MouseButtonLeft; b <= ebiten.MouseButtonMiddle; b++ {
+		if ebiten.IsMouseButtonPressed(b) {
+			frame.Buttons = append(frame.Buttons, MouseButton(b))
+		}
+	}
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if ebiten.IsKeyPressed(k) {
+			frame.Keys = append(frame.Keys, Key(k))
+		}
+	}
+	for _, id := range ebiten.TouchIDs() {
+		x, y := ebiten.TouchPosition(id)
+		frame.Touches = append(frame.Touches, TouchFrame{TouchID(id), x, y})
+	}
+	return frame
+}
+
+// PollInput captures this frame's input, from Ebiten or from a replay. Update calls it, but
+// an embedding game that reads Input before calling Game.Update should call it first so both
+// see the same frame. Calling it more than once per frame has no effect.
+func (g *Game) PollInput() {
+	g.pollInput(captureFrame)
+}
+
+func (in Input) IsMouseButtonPressed(button ebiten.MouseButton) bool {
+	return in.cur.button(MouseButton(button))
+}
+
+func (in Input) IsMouseButtonJustPressed(button ebiten.MouseButton) bool {
+	return in.mouseJustPressed(MouseButton(button))
+}
+
+func (in Input) IsMouseButtonJustReleased(button ebiten.MouseButton) bool {
+	return in.mouseJustReleased(MouseButton(button))
+}
+
+func (in Input) IsKeyPressed(key ebiten.Key) bool {
+	return in.cur.key(Key(key))
+}
+
+func (in Input) IsKeyJustPressed(key ebiten.Key) bool {
+	return in.cur.key(Key(key)) && !in.prev.key(Key(key))
+}
+
+func (in Input) JustPressedTouchIDs() []ebiten.TouchID {
+	var ids []ebiten.TouchID
+	for _, id := range in.justPressedTouches() {
+		ids = append(ids, ebiten.TouchID(id))
+	}
+	return ids
+}
+
+func (in Input) IsTouchJustReleased(id ebiten.TouchID) bool {
+	return in.touchJustReleased(TouchID(id))
+}
+
+func (in Input) TouchPosition(id ebiten.TouchID) (int, int) {
+	return in.touchPosition(TouchID(id))
+}
@@ -0,0 +1,127 @@
+package cpebiten
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// boxesGame is a stack of boxes on the floor, the bottom two pinned together.
+func boxesGame() *Game {
+	space := cp.NewSpace()
+	space.SetGravity(cp.Vector{X: 0, Y: 300})
+	AddWall(space, space.StaticBody, cp.Vector{X: 0, Y: 400}, cp.Vector{X: 600, Y: 400}, 1)
+	var bodies []*cp.Body
+	for i := 0; i < 3; i++ {
+		bodies = append(bodies, AddBox(space, cp.Vector{X: 300, Y: float64(375 - i*50)}, 1, 50, 50).Body())
+	}
+	space.AddConstraint(cp.NewPinJoint(bodies[0], bodies[1], cp.Vector{}, cp.Vector{}))
+	game := NewGame(space, 60)
+	game.Clock = NewFixedClock(1. / 60)
+	return game
+}
+
+// drag is the input of a mouse pressed on from and moved to to over the given frames.
+func drag(from, to cp.Vector, frames int) []*InputFrame {
+	var input []*InputFrame
+	for i := 0; i <= frames; i++ {
+		at := from.Lerp(to, float64(i)/float64(frames))
+		input = append(input, &InputFrame{CursorX: int(at.X), CursorY: int(at.Y), Buttons: []MouseButton{mouseButtonLeft}})
+	}
+	// let go
+	return append(input, &InputFrame{CursorX: int(to.X), CursorY: int(to.Y)})
+}
+
+func marshal(t *testing.T, game *Game) string {
+	t.Helper()
+	doc, err := MarshalSpace(game.Space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRecordReplay(t *testing.T) {
+	game := boxesGame()
+	// get partway into the session before recording, with a joint broken
+	for _, frame := range drag(cp.Vector{X: 300, Y: 275}, cp.Vector{X: 100, Y: 200}, 30) {
+		game.UpdateInput(frame)
+	}
+	game.Space.EachConstraint(func(constraint *cp.Constraint) {
+		game.Space.AddPostStepCallback(func(space *cp.Space, key, data interface{}) {
+			space.RemoveConstraint(key.(*cp.Constraint))
+		}, constraint, nil)
+	})
+	NewRunner(game).Run(1)
+
+	var log bytes.Buffer
+	if err := game.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	start := game.Ticks
+	for _, frame := range drag(cp.Vector{X: 300, Y: 375}, cp.Vector{X: 450, Y: 250}, 40) {
+		game.UpdateInput(frame)
+		if game.Rewind(1) {
+			t.Fatal("rewound while recording")
+		}
+	}
+	if err := game.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	end, want := game.Ticks, marshal(t, game)
+	if end == start {
+		t.Fatal("the recording didn't step")
+	}
+
+	// replay on a game that went somewhere else in the meantime
+	replayed := boxesGame()
+	for _, frame := range drag(cp.Vector{X: 300, Y: 325}, cp.Vector{X: 500, Y: 100}, 60) {
+		replayed.UpdateInput(frame)
+	}
+	if err := replayed.Replay(&log); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Ticks != start {
+		t.Errorf("replay started at tick %d, want %d", replayed.Ticks, start)
+	}
+	for replayed.Replaying() && len(replayed.replay) > 0 {
+		// live input is ignored while replaying
+		replayed.UpdateInput(&InputFrame{CursorX: 10, CursorY: 10, Buttons: []MouseButton{mouseButtonLeft}})
+	}
+	// the ticks after the last recorded frame aren't in the recording
+	NewRunner(replayed).Run(int(end - replayed.Ticks))
+
+	if got := marshal(t, replayed); got != want {
+		t.Errorf("replay ended in\n%s\nbut the recording in\n%s", got, want)
+	}
+}
+
+func TestReplayDifferentScene(t *testing.T) {
+	game := boxesGame()
+	var log bytes.Buffer
+	if err := game.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	game.UpdateInput(&InputFrame{})
+	if err := game.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+
+	other := fallingGame()
+	before := marshal(t, other)
+	if err := other.Replay(&log); err == nil {
+		t.Error("replayed a recording of a different scene")
+	}
+	if other.Replaying() {
+		t.Error("replaying after an error")
+	}
+	if marshal(t, other) != before {
+		t.Error("the space changed after an error")
+	}
+}
@@ -0,0 +1,57 @@
+package cpebiten
+
+import "github.com/jakecoffman/cp"
+
+type bodyTransform struct {
+	position cp.Vector
+	angle    float64
+}
+
+// recordTransforms remembers where every moving body is before the next step so drawing can
+// blend between the last two physics ticks.
+func (g *Game) recordTransforms() {
+	if g.previous == nil {
+		g.previous = map[*cp.Body]bodyTransform{}
+	}
+	for body := range g.previous {
+		delete(g.previous, body)
+	}
+	g.Space.EachBody(func(body *cp.Body) {
+		if body.GetType() == cp.BODY_STATIC {
+			return
+		}
+		g.previous[body] = bodyTransform{body.Position(), body.Angle()}
+	})
+}
+
+// DrawSpaceInterpolated draws the space with each body placed between its previous and current
+// transform, blended by Accumulator/dt. This hides the stutter that happens when the tick
+// rate and the refresh rate don't line up. Game.Interpolate must be set so the previous
+// transforms get recorded. Constraints and contact points are drawn at the current tick.
+func (g *Game) DrawSpaceInterpolated(drawer cp.Drawer) {
+	alpha := g.Accumulator * g.TicksPerSecond
+	if alpha > 1 {
+		alpha = 1
+	}
+
+	var moved []*cp.Shape
+	for body, prev := range g.previous {
+		if body.IsSleeping() {
+			continue
+		}
+		pos := prev.position.Lerp(body.Position(), alpha)
+		angle := cp.Lerp(prev.angle, body.Angle(), alpha)
+		transform := cp.NewTransformRigid(pos, angle)
+		body.EachShape(func(shape *cp.Shape) {
+			shape.Update(transform)
+			moved = append(moved, shape)
+		})
+	}
+
+	DrawSpace(g.Space, drawer)
+
+	// put the cached shape data back so the next step collides against the real transforms
+	for _, shape := range moved {
+		shape.CacheBB()
+	}
+}
@@ -1,14 +1,9 @@
 package main
 
 import (
-	"fmt"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/jakecoffman/cpebiten"
-	"image/color"
-	"log"
 	"math/rand"
 
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
 )
 
@@ -19,18 +14,15 @@ const (
 
 type Game struct {
 	*cpebiten.Game
-	dot   *ebiten.Image
 }
 
-func NewGame() *Game {
+// NewGame builds the scene, taking its randomness from rng.
+func NewGame(rng *rand.Rand) *Game {
 	const (
 		imageWidth  = 188
 		imageHeight = 35
 	)
 
-	dot := ebiten.NewImage(1, 1)
-	dot.Fill(color.White)
-
 	space := cp.NewSpace()
 	space.Iterations = 1
 
@@ -48,8 +40,8 @@ func NewGame() *Game {
 				continue
 			}
 
-			xJitter := 0.05 * rand.Float64()
-			yJitter := 0.05 * rand.Float64()
+			xJitter := 0.05 * rng.Float64()
+			yJitter := 0.05 * rng.Float64()
 
 			shape = makeBall(2.0*(float64(x)+imageWidth/2+xJitter)-75, 2*(imageHeight/2.0+float64(y)+yJitter)+150)
 			space.AddBody(shape.Body())
@@ -67,25 +59,9 @@ func NewGame() *Game {
 
 	return &Game{
 		Game: cpebiten.NewGame(space, 60),
-		dot:   dot,
 	}
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	// ebiten can't handle drawing this many triangles in one go, so drawing with image instead
-	op := &ebiten.DrawImageOptions{}
-	g.Space.EachBody(func(body *cp.Body) {
-		op.GeoM.Translate(body.Position().X, body.Position().Y)
-		screen.DrawImage(g.dot, op)
-		op.GeoM.Reset()
-	})
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f", ebiten.CurrentFPS()))
-}
-
-func (g *Game) Layout(int, int) (int, int) {
-	return screenWidth, screenHeight
-}
-
 func getPixel(x, y uint) int {
 	const imageRowLength = 24
 	return (imageBitmap[(x>>3)+y*imageRowLength] >> (^x & 0x7)) & 1
@@ -137,11 +113,3 @@ var imageBitmap = []int{
 	127, -97, -25, -8, 0, 63, -61, -61, -4, 127, -1, -29, -4, 63, -64, 15, -32, 0, 0, 23, -1, -2, 3, -16,
 	63, 15, -61, -16, 0, 31, -127, -127, -8, 31, -1, -127, -8, 31, -128, 7, -128, 0, 0,
 }
-
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Logosmash")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
-	}
-}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/jakecoffman/cpebiten"
+	"github.com/jakecoffman/cpebiten/goldentest"
+)
+
+func TestGolden(t *testing.T) {
+	goldentest.Run(t, []goldentest.Test{
+		{Name: "logosmash", Scene: func(rng *rand.Rand) *cpebiten.Game { return NewGame(rng).Game }, Ticks: 120, Width: screenWidth, Height: screenHeight},
+	})
+}
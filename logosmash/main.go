@@ -0,0 +1,23 @@
+//go:build !headless
+
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func (g *Game) Layout(int, int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+func main() {
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Logosmash")
+	if err := ebiten.RunGame(NewGame(rand.New(rand.NewSource(time.Now().UnixNano())))); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !headless
+
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/jakecoffman/cp"
+)
+
+func (g *Game) Update() error {
+	g.PollInput()
+	input := g.Input()
+	g.held = controls{
+		jump:  input.IsKeyPressed(ebiten.KeyW) || input.IsKeyPressed(ebiten.KeyUp),
+		left:  input.IsKeyPressed(ebiten.KeyA) || input.IsKeyPressed(ebiten.KeyLeft),
+		right: input.IsKeyPressed(ebiten.KeyD) || input.IsKeyPressed(ebiten.KeyRight),
+	}
+	jumpState := g.held.jump
+
+	// If the jump key was just pressed this frame, jump!
+	if jumpState && !lastJumpState && grounded {
+		jumpV := math.Sqrt(2.0 * JumpHeight * Gravity)
+		playerBody.SetVelocityVector(playerBody.Velocity().Add(cp.Vector{0, -jumpV}))
+
+		remainingBoost = JumpBoostHeight / jumpV
+	}
+
+	if err := g.Game.Update(); err != nil {
+		return err
+	}
+
+	remainingBoost -= 1. / 60.
+	lastJumpState = jumpState
+
+	return nil
+}
+
+func main() {
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Player")
+	if err := ebiten.RunGame(NewGame()); err != nil {
+		log.Fatal(err)
+	}
+}
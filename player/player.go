@@ -1,11 +1,8 @@
 package main
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
 	"github.com/jakecoffman/cpebiten"
-	"log"
-	"math"
 )
 
 const (
@@ -34,8 +31,8 @@ var playerShape *cp.Shape
 var remainingBoost float64
 var grounded, lastJumpState bool
 
-func playerUpdateVelocity(body *cp.Body, gravity cp.Vector, damping, dt float64) {
-	jumpState := ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp)
+func (game *Game) playerUpdateVelocity(body *cp.Body, gravity cp.Vector, damping, dt float64) {
+	jumpState := game.held.jump
 
 	// Grab the grounding normal from last frame
 	groundNormal := cp.Vector{}
@@ -62,10 +59,10 @@ func playerUpdateVelocity(body *cp.Body, gravity cp.Vector, damping, dt float64)
 
 	// Target horizontal speed for air/ground control
 	var targetVx float64
-	if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
+	if game.held.left {
 		targetVx -= PlayerVelocity
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
+	if game.held.right {
 		targetVx += PlayerVelocity
 	}
 
@@ -91,6 +88,13 @@ func playerUpdateVelocity(body *cp.Body, gravity cp.Vector, damping, dt float64)
 
 type Game struct {
 	*cpebiten.Game
+
+	// held is what the player is pressing, read from the input by Update
+	held controls
+}
+
+type controls struct {
+	jump, left, right bool
 }
 
 func NewGame() *Game {
@@ -98,6 +102,12 @@ func NewGame() *Game {
 	space.Iterations = 10
 	space.SetGravity(cp.Vector{0, Gravity})
 
+	game := cpebiten.NewGame(space, 180)
+	game.Interpolate = true
+	g := &Game{
+		Game: game,
+	}
+
 	walls := []cp.Vector{
 		{0, 0}, {0, screenHeight},
 		{screenWidth, 0}, {screenWidth, screenHeight},
@@ -114,7 +124,7 @@ func NewGame() *Game {
 	// player
 	playerBody = space.AddBody(cp.NewBody(1, cp.INFINITY))
 	playerBody.SetPosition(cp.Vector{100, 200})
-	playerBody.SetVelocityUpdateFunc(playerUpdateVelocity)
+	playerBody.SetVelocityUpdateFunc(g.playerUpdateVelocity)
 
 	playerShape = space.AddShape(cp.NewBox2(playerBody, cp.BB{-15, -27.5, 15, 27.5}, 10))
 	playerShape.SetElasticity(0)
@@ -132,36 +142,5 @@ func NewGame() *Game {
 		}
 	}
 
-	return &Game{
-		Game: cpebiten.NewGame(space, 180),
-	}
-}
-
-func (g *Game) Update() error {
-	jumpState := ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp)
-
-	// If the jump key was just pressed this frame, jump!
-	if jumpState && !lastJumpState && grounded {
-		jumpV := math.Sqrt(2.0 * JumpHeight * Gravity)
-		playerBody.SetVelocityVector(playerBody.Velocity().Add(cp.Vector{0, -jumpV}))
-
-		remainingBoost = JumpBoostHeight / jumpV
-	}
-
-	if err := g.Game.Update(); err != nil {
-		return err
-	}
-
-	remainingBoost -= 1./60.
-	lastJumpState = jumpState
-
-	return nil
-}
-
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Player")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
-	}
+	return g
 }
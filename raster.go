@@ -0,0 +1,21 @@
+package cpebiten
+
+import (
+	"image"
+
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten/raster"
+)
+
+// RasterizeSpace draws the space on an opaque black image of the given size with a
+// raster.Rasterizer, which needs no GPU.
+func RasterizeSpace(space *cp.Space, width, height int, theme Theme) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	r := raster.NewRasterizer(img, theme)
+	DrawSpace(space, r)
+	r.Flush()
+	return img
+}
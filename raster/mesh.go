@@ -0,0 +1,336 @@
+package raster
+
+import (
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Vertex is a corner of a triangle in a Mesh. It has the same fields as ebiten.Vertex, so one
+// converts to the other, and means the same to the debug shader: Src is the position within
+// the shape used for anti-aliasing and outlines, and Color is the fill.
+type Vertex struct {
+	DstX, DstY                     float32
+	SrcX, SrcY                     float32
+	ColorR, ColorG, ColorB, ColorA float32
+}
+
+// View is an affine transform from world coordinates to pixels:
+//
+//	x' = A*x + B*y + TX
+//	y' = C*x + D*y + TY
+//
+// The zero View leaves coordinates as they are, like a zero ebiten.GeoM.
+type View struct {
+	A, B, TX float64
+	C, D, TY float64
+}
+
+// Apply transforms a point.
+func (v View) Apply(x, y float64) (float64, float64) {
+	if v == (View{}) {
+		return x, y
+	}
+	return v.A*x + v.B*y + v.TX, v.C*x + v.D*y + v.TY
+}
+
+// PointLineScale scales the size of dots and the width of outlines.
+const PointLineScale = 1
+
+// PixelSize returns the size of one screen pixel in world units when drawing through the view.
+func (v View) PixelSize() float64 {
+	scale := math.Sqrt(math.Abs(v.A*v.D - v.B*v.C))
+	if scale == 0 {
+		scale = 1
+	}
+	return 1 / (PointLineScale * scale)
+}
+
+// Theme picks the colors a Mesh draws with. It is the same as cpebiten.Theme, so any of those
+// themes work here.
+type Theme interface {
+	OutlineColor() cp.FColor
+	ShapeColor(shape *cp.Shape) cp.FColor
+	ConstraintColor() cp.FColor
+	CollisionPointColor() cp.FColor
+}
+
+// Mesh is a cp.Drawer that turns what it is asked to draw into batches of triangles for the
+// debug shader, or for a Rasterizer that does the same on the CPU. Each finished batch is
+// passed to a draw function.
+type Mesh struct {
+	// draw receives every finished batch of triangles
+	draw func(verts []Vertex, indices []uint16)
+	view View
+	// pixel is the size of one screen pixel in world units, so outlines and anti-aliasing
+	// stay the same width on screen at any zoom.
+	pixel float64
+	flags uint
+	theme Theme
+
+	verts   []Vertex
+	indices []uint16
+	cursor  uint16
+}
+
+// NewMesh creates a mesh that transforms every vertex by view and passes each batch of
+// triangles to draw, which must be done with them by the time it returns.
+func NewMesh(view View, theme Theme, draw func(verts []Vertex, indices []uint16)) *Mesh {
+	return &Mesh{
+		draw:  draw,
+		view:  view,
+		pixel: view.PixelSize(),
+		flags: cp.DRAW_SHAPES | cp.DRAW_CONSTRAINTS | cp.DRAW_COLLISION_POINTS,
+		theme: theme,
+	}
+}
+
+// SetTheme chooses the colors things are drawn with.
+func (m *Mesh) SetTheme(theme Theme) {
+	m.theme = theme
+}
+
+// SetFlags chooses what cpebiten.DrawSpace draws, as a combination of cp.DRAW_SHAPES,
+// cp.DRAW_CONSTRAINTS and cp.DRAW_COLLISION_POINTS. The default is all of them.
+func (m *Mesh) SetFlags(flags uint) {
+	m.flags = flags
+}
+
+// Flush draws everything batched so far and starts a new, empty batch.
+func (m *Mesh) Flush() {
+	if len(m.indices) > 0 {
+		if m.view != (View{}) {
+			for i := range m.verts {
+				x, y := m.view.Apply(float64(m.verts[i].DstX), float64(m.verts[i].DstY))
+				m.verts[i].DstX, m.verts[i].DstY = float32(x), float32(y)
+			}
+		}
+		m.draw(m.verts, m.indices)
+	}
+	m.verts = m.verts[:0]
+	m.indices = m.indices[:0]
+	m.cursor = 0
+}
+
+const (
+	// maxBatchVertices is the number of vertices a uint16 index can address.
+	maxBatchVertices = math.MaxUint16 + 1
+	// MaxBatchIndices is the most indices in a batch. It is the same as ebiten.MaxIndicesNum,
+	// the most DrawTriangles takes at once.
+	MaxBatchIndices = (1 << 16) / 3 * 3
+)
+
+// reserve flushes the current batch if a primitive with the given number of vertices and
+// indices wouldn't fit in it, so spaces of any size can be drawn.
+func (m *Mesh) reserve(vertices, indices int) {
+	if len(m.verts)+vertices > maxBatchVertices || len(m.indices)+indices > MaxBatchIndices {
+		m.Flush()
+	}
+}
+
+func (m *Mesh) DrawBB(bb cp.BB, outline cp.FColor) {
+	verts := []cp.Vector{
+		{bb.R, bb.B},
+		{bb.R, bb.T},
+		{bb.L, bb.T},
+		{bb.L, bb.B},
+	}
+	m.DrawPolygon(4, verts, 0, outline, cp.FColor{}, nil)
+}
+
+func (m *Mesh) DrawCircle(pos cp.Vector, angle, radius float64, outline, fill cp.FColor, _ interface{}) {
+	r := radius + m.pixel
+
+	m.reserve(4, 6)
+	m.verts = append(m.verts,
+		Vertex{float32(pos.X - r), float32(pos.Y - r), -1, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X - r), float32(pos.Y + r), -1, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X + r), float32(pos.Y + r), 1, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X + r), float32(pos.Y - r), 1, -1, fill.R, fill.G, fill.B, fill.A},
+	)
+	m.indices = append(m.indices,
+		m.cursor+0, m.cursor+1, m.cursor+2,
+		m.cursor+0, m.cursor+2, m.cursor+3,
+	)
+	m.cursor += 4
+
+	m.DrawFatSegment(pos, pos.Add(cp.ForAngle(angle).Mult(radius-m.pixel*0.5)), 0, outline, fill, nil)
+}
+
+func (m *Mesh) DrawSegment(a, b cp.Vector, fill cp.FColor, data interface{}) {
+	m.DrawFatSegment(a, b, 0, fill, fill, data)
+}
+
+func (m *Mesh) DrawFatSegment(a, b cp.Vector, radius float64, outline, fill cp.FColor, _ interface{}) {
+	n := b.Sub(a).ReversePerp().Normalize()
+	t := n.ReversePerp()
+
+	half := m.pixel
+	r := radius + half
+
+	if r <= half {
+		r = half
+		fill = outline
+	}
+
+	nw := n.Mult(r)
+	tw := t.Mult(r)
+	v0 := b.Sub(nw.Add(tw))
+	v1 := b.Add(nw.Sub(tw))
+	v2 := b.Sub(nw)
+	v3 := b.Add(nw)
+	v4 := a.Sub(nw)
+	v5 := a.Add(nw)
+	v6 := a.Sub(nw.Sub(tw))
+	v7 := a.Add(nw.Add(tw))
+
+	m.reserve(8, 18)
+	m.verts = append(m.verts,
+		Vertex{float32(v0.X), float32(v0.Y), 1, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v1.X), float32(v1.Y), 1, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v2.X), float32(v2.Y), 0, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v3.X), float32(v3.Y), 0, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v4.X), float32(v4.Y), 0, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v5.X), float32(v5.Y), 0, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v6.X), float32(v6.Y), -1, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(v7.X), float32(v7.Y), -1, 1, fill.R, fill.G, fill.B, fill.A},
+	)
+
+	m.indices = append(m.indices,
+		m.cursor+0, m.cursor+1, m.cursor+2,
+		m.cursor+3, m.cursor+1, m.cursor+2,
+		m.cursor+3, m.cursor+4, m.cursor+2,
+		m.cursor+3, m.cursor+4, m.cursor+5,
+		m.cursor+6, m.cursor+4, m.cursor+5,
+		m.cursor+6, m.cursor+7, m.cursor+5,
+	)
+	m.cursor += 8
+}
+
+func (m *Mesh) DrawPolygon(count int, verts []cp.Vector, radius float64, outline, fill cp.FColor, _ interface{}) {
+	type ExtrudeVerts struct {
+		offset, n cp.Vector
+	}
+	extrude := make([]ExtrudeVerts, count)
+
+	for i := 0; i < count; i++ {
+		v0 := verts[(i-1+count)%count]
+		v1 := verts[i]
+		v2 := verts[(i+1)%count]
+
+		n1 := v1.Sub(v0).ReversePerp().Normalize()
+		n2 := v2.Sub(v1).ReversePerp().Normalize()
+
+		offset := n1.Add(n2).Mult(1.0 / (n1.Dot(n2) + 1.0))
+		extrude[i] = ExtrudeVerts{offset, n2}
+	}
+
+	inset := -math.Max(0, m.pixel-radius)
+	for i := 0; i < count-2; i++ {
+		v0 := verts[0].Add(extrude[0].offset.Mult(inset))
+		v1 := verts[i+1].Add(extrude[i+1].offset.Mult(inset))
+		v2 := verts[i+2].Add(extrude[i+2].offset.Mult(inset))
+
+		m.reserve(3, 3)
+		m.verts = append(m.verts,
+			Vertex{float32(v0.X), float32(v0.Y), 0, 0, fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(v1.X), float32(v1.Y), 0, 0, fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(v2.X), float32(v2.Y), 0, 0, fill.R, fill.G, fill.B, fill.A},
+		)
+
+		m.indices = append(m.indices, m.cursor+0, m.cursor+1, m.cursor+2)
+		m.cursor += 3
+	}
+
+	outset := m.pixel + radius - inset
+	j := count - 1
+	for i := 0; i < count; {
+		vA := verts[i]
+		vB := verts[j]
+
+		nA := extrude[i].n
+		nB := extrude[j].n
+
+		offsetA := extrude[i].offset
+		offsetB := extrude[j].offset
+
+		innerA := vA.Add(offsetA.Mult(inset))
+		innerB := vB.Add(offsetB.Mult(inset))
+
+		inner0 := innerA
+		inner1 := innerB
+		outer0 := innerA.Add(nB.Mult(outset))
+		outer1 := innerB.Add(nB.Mult(outset))
+		outer2 := innerA.Add(offsetA.Mult(outset))
+		outer3 := innerA.Add(nA.Mult(outset))
+
+		n0 := nA
+		n1 := nB
+		offset0 := offsetA
+
+		m.reserve(6, 12)
+		m.verts = append(m.verts,
+			Vertex{float32(inner0.X), float32(inner0.Y), 0, 0, fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(inner1.X), float32(inner1.Y), 0, 0, fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(outer0.X), float32(outer0.Y), float32(n1.X), float32(n1.Y), fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(outer1.X), float32(outer1.Y), float32(n1.X), float32(n1.Y), fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(outer2.X), float32(outer2.Y), float32(offset0.X), float32(offset0.Y), fill.R, fill.G, fill.B, fill.A},
+			Vertex{float32(outer3.X), float32(outer3.Y), float32(n0.X), float32(n0.Y), fill.R, fill.G, fill.B, fill.A},
+		)
+
+		m.indices = append(m.indices,
+			m.cursor+0, m.cursor+1, m.cursor+3,
+			m.cursor+0, m.cursor+2, m.cursor+3,
+			m.cursor+0, m.cursor+2, m.cursor+4,
+			m.cursor+0, m.cursor+4, m.cursor+5,
+		)
+
+		m.cursor += 6
+
+		j = i
+		i++
+	}
+}
+
+func (m *Mesh) DrawDot(size float64, pos cp.Vector, fill cp.FColor, _ interface{}) {
+	r := size * 0.5 * m.pixel
+
+	m.reserve(4, 6)
+	m.verts = append(m.verts,
+		Vertex{float32(pos.X - r), float32(pos.Y - r), -1, -1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X - r), float32(pos.Y + r), -1, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X + r), float32(pos.Y + r), 1, 1, fill.R, fill.G, fill.B, fill.A},
+		Vertex{float32(pos.X + r), float32(pos.Y - r), 1, -1, fill.R, fill.G, fill.B, fill.A},
+	)
+
+	m.indices = append(m.indices,
+		m.cursor+0, m.cursor+1, m.cursor+2,
+		m.cursor+0, m.cursor+2, m.cursor+3,
+	)
+
+	m.cursor += 4
+}
+
+func (m *Mesh) Flags() uint {
+	return m.flags
+}
+
+func (m *Mesh) OutlineColor() cp.FColor {
+	return m.theme.OutlineColor()
+}
+
+func (m *Mesh) ShapeColor(shape *cp.Shape, _ interface{}) cp.FColor {
+	return m.theme.ShapeColor(shape)
+}
+
+func (m *Mesh) ConstraintColor() cp.FColor {
+	return m.theme.ConstraintColor()
+}
+
+func (m *Mesh) CollisionPointColor() cp.FColor {
+	return m.theme.CollisionPointColor()
+}
+
+func (m *Mesh) Data() interface{} {
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package raster draws spaces the way cpebiten's debug shader does, without Ebiten or a GPU.
+// Mesh turns a space's debug drawing into triangles and Rasterizer fills them into an
+// *image.RGBA, which is enough to render scenes to PNG on a machine with no display.
+package raster
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Rasterizer is a cp.Drawer that draws the same geometry as cpebiten.DrawOptions into an
+// *image.RGBA on the CPU, reproducing the anti-aliased fill and outline of the debug shader
+// in pure Go. It is useful for rendering scenes to PNG where there is no GPU, such as in CI.
+type Rasterizer struct {
+	*Mesh
+	img *image.RGBA
+}
+
+// NewRasterizer creates a rasterizer that draws into img with the colors of theme. Call Flush
+// when done drawing.
+func NewRasterizer(img *image.RGBA, theme Theme) *Rasterizer {
+	return NewRasterizerView(img, View{}, theme)
+}
+
+// NewRasterizerView creates a rasterizer that transforms every vertex by view before drawing.
+func NewRasterizerView(img *image.RGBA, view View, theme Theme) *Rasterizer {
+	r := &Rasterizer{img: img}
+	r.Mesh = NewMesh(view, theme, r.drawTriangles)
+	return r
+}
+
+func (r *Rasterizer) drawTriangles(verts []Vertex, indices []uint16) {
+	for i := 0; i+2 < len(indices); i += 3 {
+		r.drawTriangle(&verts[indices[i]], &verts[indices[i+1]], &verts[indices[i+2]])
+	}
+}
+
+// edge is positive when p is to the right of a->b in screen space (y down).
+func edge(ax, ay, bx, by, px, py float64) float64 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// ownsEdge breaks ties for pixels exactly on an edge. Two triangles sharing an edge walk it in
+// opposite directions, so exactly one of them draws those pixels.
+func ownsEdge(ax, ay, bx, by float64) bool {
+	return by > ay || (by == ay && bx < ax)
+}
+
+func (r *Rasterizer) drawTriangle(v0, v1, v2 *Vertex) {
+	x0, y0 := float64(v0.DstX), float64(v0.DstY)
+	x1, y1 := float64(v1.DstX), float64(v1.DstY)
+	x2, y2 := float64(v2.DstX), float64(v2.DstY)
+
+	area := edge(x0, y0, x1, y1, x2, y2)
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		v1, v2 = v2, v1
+		x1, y1, x2, y2 = x2, y2, x1, y1
+		area = -area
+	}
+
+	// the aa attribute is linear across the triangle, so its screen space derivative (what
+	// fwidth gives the shader) is the same for every pixel
+	du1, du2 := float64(v1.SrcX-v0.SrcX), float64(v2.SrcX-v0.SrcX)
+	dv1, dv2 := float64(v1.SrcY-v0.SrcY), float64(v2.SrcY-v0.SrcY)
+	dudx := (du1*(y2-y0) - du2*(y1-y0)) / area
+	dudy := (du2*(x1-x0) - du1*(x2-x0)) / area
+	dvdx := (dv1*(y2-y0) - dv2*(y1-y0)) / area
+	dvdy := (dv2*(x1-x0) - dv1*(x2-x0)) / area
+	fw := math.Hypot(math.Abs(dudx)+math.Abs(dudy), math.Abs(dvdx)+math.Abs(dvdy))
+
+	bounds := r.img.Bounds()
+	minX := int(math.Max(math.Floor(math.Min(x0, math.Min(x1, x2))), float64(bounds.Min.X)))
+	maxX := int(math.Min(math.Ceil(math.Max(x0, math.Max(x1, x2))), float64(bounds.Max.X-1)))
+	minY := int(math.Max(math.Floor(math.Min(y0, math.Min(y1, y2))), float64(bounds.Min.Y)))
+	maxY := int(math.Min(math.Ceil(math.Max(y0, math.Max(y1, y2))), float64(bounds.Max.Y-1)))
+
+	own0 := ownsEdge(x1, y1, x2, y2)
+	own1 := ownsEdge(x2, y2, x0, y0)
+	own2 := ownsEdge(x0, y0, x1, y1)
+
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+
+			w0 := edge(x1, y1, x2, y2, px, py)
+			w1 := edge(x2, y2, x0, y0, px, py)
+			w2 := edge(x0, y0, x1, y1, px, py)
+			if w0 < 0 || w1 < 0 || w2 < 0 ||
+				(w0 == 0 && !own0) || (w1 == 0 && !own1) || (w2 == 0 && !own2) {
+				continue
+			}
+			w0, w1, w2 = w0/area, w1/area, w2/area
+
+			u := w0*float64(v0.SrcX) + w1*float64(v1.SrcX) + w2*float64(v2.SrcX)
+			v := w0*float64(v0.SrcY) + w1*float64(v1.SrcY) + w2*float64(v2.SrcY)
+			c := [4]float64{
+				w0*float64(v0.ColorR) + w1*float64(v1.ColorR) + w2*float64(v2.ColorR),
+				w0*float64(v0.ColorG) + w1*float64(v1.ColorG) + w2*float64(v2.ColorG),
+				w0*float64(v0.ColorB) + w1*float64(v1.ColorB) + w2*float64(v2.ColorB),
+				w0*float64(v0.ColorA) + w1*float64(v1.ColorA) + w2*float64(v2.ColorA),
+			}
+			r.blend(x, y, shade(u, v, fw, c))
+		}
+	}
+}
+
+// shade is the debug shader's Fragment function. Its result is blended like a pre-multiplied
+// color, though the alpha it returns is multiplied by the color's alpha a second time.
+func shade(u, v, fw float64, c [4]float64) [4]float64 {
+	l := math.Hypot(u, v)
+
+	// Outline width threshold.
+	ow := 1 - fw
+
+	// Fill/outline color.
+	foStep := smoothstep(math.Max(ow-fw, 0), ow, l)
+	for i := range c {
+		c[i] = c[i]*(1-foStep) + foStep
+	}
+
+	// Use pre-multiplied alpha.
+	alpha := 1 - smoothstep(1-fw, 1, l)
+	a := c[3] * alpha
+	return [4]float64{c[0] * a, c[1] * a, c[2] * a, c[3] * a}
+}
+
+func smoothstep(e0, e1, x float64) float64 {
+	if e0 == e1 {
+		if x < e0 {
+			return 0
+		}
+		return 1
+	}
+	t := math.Max(0, math.Min(1, (x-e0)/(e1-e0)))
+	return t * t * (3 - 2*t)
+}
+
+// blend draws a pre-multiplied color over the pixel, like Ebiten's default composite mode.
+func (r *Rasterizer) blend(x, y int, src [4]float64) {
+	if src[3] <= 0 {
+		return
+	}
+	dst := r.img.RGBAAt(x, y)
+	inv := 1 - math.Min(src[3], 1)
+	r.img.SetRGBA(x, y, color.RGBA{
+		R: toByte(src[0]*255 + float64(dst.R)*inv),
+		G: toByte(src[1]*255 + float64(dst.G)*inv),
+		B: toByte(src[2]*255 + float64(dst.B)*inv),
+		A: toByte(src[3]*255 + float64(dst.A)*inv),
+	})
+}
+
+func toByte(f float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(f))))
+}
@@ -0,0 +1,74 @@
+package raster
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func TestShade(t *testing.T) {
+	tests := []struct {
+		name  string
+		u, v  float64
+		color [4]float64
+		want  [4]float64
+	}{
+		{"opaque inside", 0, 0, [4]float64{1, 0.5, 0, 1}, [4]float64{1, 0.5, 0, 1}},
+		// the shader returns fo_color*(fo_color.a*alpha), so alpha ends up squared
+		{"translucent inside", 0, 0, [4]float64{1, 0.5, 0, 0.5}, [4]float64{0.5, 0.25, 0, 0.25}},
+		{"outside", 2, 0, [4]float64{1, 0.5, 0, 1}, [4]float64{0, 0, 0, 0}},
+		// the outline is white at the color's full opacity
+		{"outline", 0.95, 0, [4]float64{1, 0.5, 0, 1}, [4]float64{1, 1, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := shade(test.u, test.v, 0.05, test.color)
+			for i := range got {
+				if math.Abs(got[i]-test.want[i]) > 1e-9 {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRasterizerPolygon(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	r := NewRasterizer(img, nil)
+	square := []cp.Vector{{10, 10}, {10, 30}, {30, 30}, {30, 10}}
+	fill := cp.FColor{R: 1, A: 1}
+	r.DrawPolygon(len(square), square, 0, fill, fill, nil)
+	r.Flush()
+
+	if got, want := img.RGBAAt(20, 20), (color.RGBA{255, 0, 0, 255}); got != want {
+		t.Errorf("inside is %v, want %v", got, want)
+	}
+	if got, want := img.RGBAAt(5, 5), (color.RGBA{}); got != want {
+		t.Errorf("outside is %v, want %v", got, want)
+	}
+}
+
+func TestViewPixelSize(t *testing.T) {
+	tests := []struct {
+		name string
+		view View
+		want float64
+	}{
+		{"zero", View{}, 1},
+		{"identity", View{A: 1, D: 1}, 1},
+		{"zoomed in", View{A: 4, D: 4, TX: 100}, 0.25},
+		{"rotated", View{A: 0, B: -2, C: 2, D: 0}, 0.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.view.PixelSize(); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package cpebiten
+
+// Runner advances a Game without opening a window, for tests and CI machines that have no
+// display. It never calls ebiten.RunGame, only Game.Step.
+type Runner struct {
+	Game *Game
+
+	// BeforeTick is an optional callback that is called before each tick with the tick number.
+	BeforeTick func(tick uint64)
+	// AfterTick is an optional callback that is called after each tick with the tick number.
+	AfterTick func(tick uint64)
+}
+
+// NewRunner creates a runner for the game.
+func NewRunner(game *Game) *Runner {
+	return &Runner{
+		Game: game,
+	}
+}
+
+// Tick advances the game by a single fixed step.
+func (r *Runner) Tick() {
+	tick := r.Game.Ticks
+	if r.BeforeTick != nil {
+		r.BeforeTick(tick)
+	}
+	r.Game.Step()
+	if r.AfterTick != nil {
+		r.AfterTick(tick)
+	}
+}
+
+// Run advances the game by n ticks.
+func (r *Runner) Run(n int) {
+	for i := 0; i < n; i++ {
+		r.Tick()
+	}
+}
+
+// RunUntil ticks the game until done returns true or maxTicks have passed. It returns the
+// number of ticks taken and whether done was satisfied. done is checked before every tick,
+// so a game that already satisfies it is not stepped at all.
+func (r *Runner) RunUntil(done func(game *Game) bool, maxTicks int) (int, bool) {
+	for i := 0; i < maxTicks; i++ {
+		if done(r.Game) {
+			return i, true
+		}
+		r.Tick()
+	}
+	return maxTicks, done(r.Game)
+}
@@ -0,0 +1,84 @@
+package cpebiten
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// fallingGame is a ball dropped from rest, for counting ticks against.
+func fallingGame() *Game {
+	space := cp.NewSpace()
+	space.SetGravity(cp.Vector{X: 0, Y: 100})
+	body := space.AddBody(cp.NewBody(1, cp.MomentForCircle(1, 0, 10, cp.Vector{})))
+	space.AddShape(cp.NewCircle(body, 10, cp.Vector{}))
+	return NewGame(space, 60)
+}
+
+func ballY(game *Game) float64 {
+	var y float64
+	game.Space.EachBody(func(body *cp.Body) {
+		if body.GetType() == cp.BODY_DYNAMIC {
+			y = body.Position().Y
+		}
+	})
+	return y
+}
+
+func TestRunnerRun(t *testing.T) {
+	game := fallingGame()
+	runner := NewRunner(game)
+	var before, after []uint64
+	runner.BeforeTick = func(tick uint64) { before = append(before, tick) }
+	runner.AfterTick = func(tick uint64) { after = append(after, tick) }
+
+	runner.Run(3)
+	runner.Run(2)
+
+	if game.Ticks != 5 {
+		t.Errorf("got %d ticks, want 5", game.Ticks)
+	}
+	want := []uint64{0, 1, 2, 3, 4}
+	if !reflect.DeepEqual(before, want) {
+		t.Errorf("BeforeTick got %v, want %v", before, want)
+	}
+	if !reflect.DeepEqual(after, want) {
+		t.Errorf("AfterTick got %v, want %v", after, want)
+	}
+	if ballY(game) <= 0 {
+		t.Errorf("ball at %v after 5 ticks, want it to have fallen", ballY(game))
+	}
+}
+
+func TestRunnerRunUntil(t *testing.T) {
+	tests := []struct {
+		name      string
+		done      func(game *Game) bool
+		maxTicks  int
+		wantTicks int
+		wantDone  bool
+	}{
+		{"already done", func(game *Game) bool { return true }, 10, 0, true},
+		{"done after ticks", func(game *Game) bool { return game.Ticks == 7 }, 10, 7, true},
+		{"done on the last tick", func(game *Game) bool { return game.Ticks == 10 }, 10, 10, true},
+		{"never done", func(game *Game) bool { return false }, 10, 10, false},
+		{"ball falls", func(game *Game) bool { return ballY(game) > 50 }, 600, 61, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := fallingGame()
+			ticks, done := NewRunner(game).RunUntil(test.done, test.maxTicks)
+			if done != test.wantDone {
+				t.Errorf("got done %v, want %v", done, test.wantDone)
+			}
+			if ticks != test.wantTicks {
+				t.Errorf("got %d ticks, want %d", ticks, test.wantTicks)
+			}
+			if uint64(ticks) != game.Ticks {
+				t.Errorf("returned %d ticks but the game took %d", ticks, game.Ticks)
+			}
+		})
+	}
+}
@@ -0,0 +1,587 @@
+package cpebiten
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/jakecoffman/cp"
+)
+
+// SpaceFormatVersion is the version written to, and required when reading, the JSON scene format.
+const SpaceFormatVersion = 1
+
+// SpaceJSON is the on-disk form of a cp.Space written by SaveSpace and read by LoadSpace.
+// Bodies are referred to by id. Id 0 is always the space's StaticBody.
+type SpaceJSON struct {
+	Version            int              `json:"version"`
+	Iterations         uint             `json:"iterations"`
+	Gravity            cp.Vector        `json:"gravity"`
+	Damping            float64          `json:"damping"`
+	SleepTimeThreshold float64          `json:"sleepTimeThreshold"`
+	CollisionSlop      float64          `json:"collisionSlop"`
+	Bodies             []BodyJSON       `json:"bodies"`
+	Shapes             []ShapeJSON      `json:"shapes"`
+	Constraints        []ConstraintJSON `json:"constraints,omitempty"`
+}
+
+type BodyJSON struct {
+	ID              int       `json:"id"`
+	Type            string    `json:"type"`
+	Mass            float64   `json:"mass,omitempty"`
+	Moment          float64   `json:"moment,omitempty"`
+	Position        cp.Vector `json:"position"`
+	Angle           float64   `json:"angle,omitempty"`
+	Velocity        cp.Vector `json:"velocity"`
+	AngularVelocity float64   `json:"angularVelocity,omitempty"`
+	// Detached bodies own shapes in the space without being added to it themselves, like a
+	// static body made with cp.NewStaticBody.
+	Detached bool `json:"detached,omitempty"`
+}
+
+type ShapeJSON struct {
+	Body int    `json:"body"`
+	Type string `json:"type"`
+
+	// circle
+	Offset *cp.Vector `json:"offset,omitempty"`
+	// segment
+	A *cp.Vector `json:"a,omitempty"`
+	B *cp.Vector `json:"b,omitempty"`
+	// poly
+	Verts []cp.Vector `json:"verts,omitempty"`
+
+	Radius          float64        `json:"radius"`
+	Friction        float64        `json:"friction"`
+	Elasticity      float64        `json:"elasticity"`
+	SurfaceVelocity cp.Vector      `json:"surfaceVelocity"`
+	Sensor          bool           `json:"sensor,omitempty"`
+	CollisionType   uint           `json:"collisionType,omitempty"`
+	Filter          cp.ShapeFilter `json:"filter"`
+}
+
+// ConstraintJSON holds the parameters of every kind of constraint. Only the ones that apply to
+// Type are used.
+type ConstraintJSON struct {
+	Type string `json:"type"`
+	A    int    `json:"a"`
+	B    int    `json:"b"`
+
+	MaxForce      float64 `json:"maxForce"`
+	MaxBias       float64 `json:"maxBias"`
+	ErrorBias     float64 `json:"errorBias"`
+	CollideBodies bool    `json:"collideBodies"`
+
+	AnchorA    *cp.Vector `json:"anchorA,omitempty"`
+	AnchorB    *cp.Vector `json:"anchorB,omitempty"`
+	GrooveA    *cp.Vector `json:"grooveA,omitempty"`
+	GrooveB    *cp.Vector `json:"grooveB,omitempty"`
+	Dist       float64    `json:"dist,omitempty"`
+	Min        float64    `json:"min,omitempty"`
+	Max        float64    `json:"max,omitempty"`
+	RestLength float64    `json:"restLength,omitempty"`
+	RestAngle  float64    `json:"restAngle,omitempty"`
+	Stiffness  float64    `json:"stiffness,omitempty"`
+	Damping    float64    `json:"damping,omitempty"`
+	Angle      float64    `json:"angle,omitempty"`
+	Phase      float64    `json:"phase,omitempty"`
+	Ratchet    float64    `json:"ratchet,omitempty"`
+	Ratio      float64    `json:"ratio,omitempty"`
+	Rate       float64    `json:"rate,omitempty"`
+}
+
+var bodyTypeNames = map[int]string{
+	cp.BODY_DYNAMIC:   "dynamic",
+	cp.BODY_KINEMATIC: "kinematic",
+	cp.BODY_STATIC:    "static",
+}
+
+// SaveSpace writes the space as JSON. Callbacks such as velocity functions, collision
+// handlers and constraint PreSolve/PostSolve can't be saved, and constraints attached to
+// bodies that are neither in the space nor own a shape in it (such as the mouse body) are
+// skipped.
+func SaveSpace(w io.Writer, space *cp.Space) error {
+	doc, err := MarshalSpace(space)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// SaveSpaceFile is a convenience for SaveSpace that writes to a file.
+func SaveSpaceFile(path string, space *cp.Space) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = SaveSpace(f, space); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// MarshalSpace converts the space into its JSON document form.
+func MarshalSpace(space *cp.Space) (*SpaceJSON, error) {
+	var f fields
+	doc := &SpaceJSON{
+		Version:            SpaceFormatVersion,
+		Iterations:         space.Iterations,
+		Gravity:            space.Gravity(),
+		Damping:            space.Damping(),
+		SleepTimeThreshold: space.SleepTimeThreshold,
+		CollisionSlop:      f.float(space, "collisionSlop"),
+	}
+
+	bodies, shapes := spaceBodies(space)
+	ids := make(map[*cp.Body]int, len(bodies))
+	for id, body := range bodies {
+		ids[body] = id
+	}
+
+	doc.Bodies = append(doc.Bodies, BodyJSON{ID: 0, Type: "static", Position: space.StaticBody.Position()})
+	for id, body := range bodies[1:] {
+		b := BodyJSON{
+			ID:              id + 1,
+			Type:            bodyTypeNames[body.GetType()],
+			Position:        body.Position(),
+			Angle:           body.Angle(),
+			Velocity:        body.Velocity(),
+			AngularVelocity: body.AngularVelocity(),
+			Detached:        !space.ContainsBody(body),
+		}
+		if body.GetType() == cp.BODY_DYNAMIC {
+			b.Mass = body.Mass()
+			b.Moment = body.Moment()
+		}
+		doc.Bodies = append(doc.Bodies, b)
+	}
+
+	for _, shape := range shapes {
+		s := ShapeJSON{
+			Body:            ids[shape.Body()],
+			Friction:        shape.Friction(),
+			Elasticity:      shape.Elasticity(),
+			SurfaceVelocity: f.vector(shape, "surfaceV"),
+			Sensor:          shape.Sensor(),
+			CollisionType:   uint(f.uint(shape, "collisionType")),
+			Filter:          shape.Filter,
+		}
+		switch class := shape.Class.(type) {
+		case *cp.Circle:
+			s.Type = "circle"
+			s.Offset = vector(f.vector(class, "c"))
+			s.Radius = class.Radius()
+		case *cp.Segment:
+			s.Type = "segment"
+			s.A = vector(class.A())
+			s.B = vector(class.B())
+			s.Radius = class.Radius()
+		case *cp.PolyShape:
+			s.Type = "poly"
+			for i := 0; i < class.Count(); i++ {
+				s.Verts = append(s.Verts, class.Vert(i))
+			}
+			s.Radius = class.Radius()
+		default:
+			return nil, fmt.Errorf("unsupported shape %T", shape.Class)
+		}
+		doc.Shapes = append(doc.Shapes, s)
+	}
+
+	byPointer := bodyPointers(bodies)
+	var err error
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		c, ok, e := marshalConstraint(constraint, byPointer, &f)
+		if !ok {
+			return
+		}
+		if e != nil {
+			err = e
+		}
+		doc.Constraints = append(doc.Constraints, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return doc, nil
+}
+
+// spaceBodies returns the bodies of the space indexed by their id in the JSON form, and its
+// shapes in the order they are written. Bodies that own shapes in the space without being in
+// it come after the ones that are.
+func spaceBodies(space *cp.Space) ([]*cp.Body, []*cp.Shape) {
+	bodies := []*cp.Body{space.StaticBody}
+	seen := map[*cp.Body]bool{space.StaticBody: true}
+	addBody := func(body *cp.Body) {
+		if !seen[body] {
+			seen[body] = true
+			bodies = append(bodies, body)
+		}
+	}
+	space.EachBody(addBody)
+
+	// the spatial index doesn't iterate in a stable order, so go by creation order instead
+	var shapes []*cp.Shape
+	space.EachShape(func(shape *cp.Shape) {
+		shapes = append(shapes, shape)
+	})
+	sort.Slice(shapes, func(i, j int) bool {
+		return shapes[i].HashId() < shapes[j].HashId()
+	})
+	for _, shape := range shapes {
+		addBody(shape.Body())
+	}
+	return bodies, shapes
+}
+
+// bodyPointers maps the address of each body to its id, to look up the bodies of a
+// constraint, which cp doesn't have getters for.
+func bodyPointers(bodies []*cp.Body) map[uintptr]int {
+	byPointer := make(map[uintptr]int, len(bodies))
+	for id, body := range bodies {
+		byPointer[reflect.ValueOf(body).Pointer()] = id
+	}
+	return byPointer
+}
+
+// marshalConstraint converts a constraint into its JSON form. It returns false if one of the
+// constraint's bodies isn't in byPointer, such as the mouse body.
+func marshalConstraint(constraint *cp.Constraint, byPointer map[uintptr]int, f *fields) (ConstraintJSON, bool, error) {
+	a, okA := byPointer[f.pointer(constraint, "a")]
+	b, okB := byPointer[f.pointer(constraint, "b")]
+	if !okA || !okB {
+		return ConstraintJSON{}, false, nil
+	}
+	c := ConstraintJSON{
+		A:             a,
+		B:             b,
+		MaxForce:      constraint.MaxForce(),
+		MaxBias:       constraint.MaxBias(),
+		ErrorBias:     constraint.ErrorBias(),
+		CollideBodies: f.bool(constraint, "collideBodies"),
+	}
+	switch class := constraint.Class.(type) {
+	case *cp.PinJoint:
+		c.Type = "pin"
+		c.AnchorA, c.AnchorB, c.Dist = vector(class.AnchorA), vector(class.AnchorB), class.Dist
+	case *cp.SlideJoint:
+		c.Type = "slide"
+		c.AnchorA, c.AnchorB, c.Min, c.Max = vector(class.AnchorA), vector(class.AnchorB), class.Min, class.Max
+	case *cp.PivotJoint:
+		c.Type = "pivot"
+		c.AnchorA, c.AnchorB = vector(class.AnchorA), vector(class.AnchorB)
+	case *cp.GrooveJoint:
+		c.Type = "groove"
+		c.GrooveA, c.GrooveB, c.AnchorB = vector(class.GrooveA), vector(class.GrooveB), vector(class.AnchorB)
+	case *cp.DampedSpring:
+		c.Type = "dampedSpring"
+		c.AnchorA, c.AnchorB = vector(class.AnchorA), vector(class.AnchorB)
+		c.RestLength, c.Stiffness, c.Damping = class.RestLength, class.Stiffness, class.Damping
+	case *cp.DampedRotarySpring:
+		c.Type = "dampedRotarySpring"
+		c.RestAngle, c.Stiffness, c.Damping = class.RestAngle, class.Stiffness, class.Damping
+	case *cp.RotaryLimitJoint:
+		c.Type = "rotaryLimit"
+		c.Min, c.Max = class.Min, class.Max
+	case *cp.RatchetJoint:
+		c.Type = "ratchet"
+		c.Angle, c.Phase, c.Ratchet = class.Angle, class.Phase, class.Ratchet
+	case *cp.GearJoint:
+		c.Type = "gear"
+		c.Phase = f.float(class, "phase")
+		c.Ratio = f.float(class, "ratio")
+	case *cp.SimpleMotor:
+		c.Type = "simpleMotor"
+		c.Rate = class.Rate
+	default:
+		return c, true, fmt.Errorf("unsupported constraint %T", constraint.Class)
+	}
+	return c, true, nil
+}
+
+// restoreSpace puts the bodies of the space back in the state doc has them in and removes the
+// constraints that doc doesn't have, such as joints that had broken by the time doc was
+// taken. Unlike UnmarshalSpace it keeps the space's own bodies, shapes and callbacks, so the
+// space must hold the same scene doc was taken from: the bodies have to line up by id, and
+// every constraint in doc must still be in the space. Nothing is changed if they don't.
+func restoreSpace(space *cp.Space, doc *SpaceJSON) error {
+	bodies, _ := spaceBodies(space)
+	if len(bodies) != len(doc.Bodies) {
+		return fmt.Errorf("the space has %d bodies but the saved one has %d", len(bodies), len(doc.Bodies))
+	}
+	for id, b := range doc.Bodies {
+		if b.ID != id {
+			return fmt.Errorf("body %d has id %d", id, b.ID)
+		}
+		if typ := bodyTypeNames[bodies[id].GetType()]; typ != b.Type {
+			return fmt.Errorf("body %d is %s in the space but %s in the saved one", id, typ, b.Type)
+		}
+	}
+
+	byPointer := bodyPointers(bodies)
+	found := make([]bool, len(doc.Constraints))
+	var f fields
+	var extra []*cp.Constraint
+	var err error
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		c, ok, e := marshalConstraint(constraint, byPointer, &f)
+		if !ok {
+			return
+		}
+		if e != nil {
+			err = e
+			return
+		}
+		for i := range doc.Constraints {
+			if !found[i] && reflect.DeepEqual(c, doc.Constraints[i]) {
+				found[i] = true
+				return
+			}
+		}
+		extra = append(extra, constraint)
+	})
+	if err != nil {
+		return err
+	}
+	if f.err != nil {
+		return f.err
+	}
+	for i, ok := range found {
+		if !ok {
+			return fmt.Errorf("constraint %d isn't in the space", i)
+		}
+	}
+
+	for _, constraint := range extra {
+		space.RemoveConstraint(constraint)
+	}
+	for id, b := range doc.Bodies {
+		body := bodies[id]
+		if body.GetType() == cp.BODY_STATIC {
+			continue
+		}
+		body.SetAngle(b.Angle)
+		body.SetPosition(b.Position)
+		body.SetVelocityVector(b.Velocity)
+		body.SetAngularVelocity(b.AngularVelocity)
+		body.EachShape(func(shape *cp.Shape) {
+			shape.CacheBB()
+		})
+	}
+	return nil
+}
+
+// LoadSpace reads a space written by SaveSpace.
+func LoadSpace(r io.Reader) (*cp.Space, error) {
+	var doc SpaceJSON
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return UnmarshalSpace(&doc)
+}
+
+// LoadSpaceFile is a convenience for LoadSpace that reads from a file.
+func LoadSpaceFile(path string) (*cp.Space, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadSpace(f)
+}
+
+// UnmarshalSpace builds a new space from its JSON document form.
+func UnmarshalSpace(doc *SpaceJSON) (*cp.Space, error) {
+	if doc.Version != SpaceFormatVersion {
+		return nil, fmt.Errorf("unsupported space format version %d, want %d", doc.Version, SpaceFormatVersion)
+	}
+
+	space := cp.NewSpace()
+	space.Iterations = doc.Iterations
+	space.SetGravity(doc.Gravity)
+	space.SetDamping(doc.Damping)
+	space.SleepTimeThreshold = doc.SleepTimeThreshold
+	space.SetCollisionSlop(doc.CollisionSlop)
+
+	bodies := map[int]*cp.Body{0: space.StaticBody}
+	for _, b := range doc.Bodies {
+		if b.ID == 0 {
+			continue
+		}
+		if _, ok := bodies[b.ID]; ok {
+			return nil, fmt.Errorf("duplicate body id %d", b.ID)
+		}
+		var body *cp.Body
+		switch b.Type {
+		case "dynamic":
+			body = cp.NewBody(b.Mass, b.Moment)
+		case "kinematic":
+			body = cp.NewKinematicBody()
+		case "static":
+			body = cp.NewStaticBody()
+		default:
+			return nil, fmt.Errorf("body %d: unknown type %q", b.ID, b.Type)
+		}
+		if !b.Detached {
+			space.AddBody(body)
+		}
+		body.SetAngle(b.Angle)
+		body.SetPosition(b.Position)
+		body.SetVelocityVector(b.Velocity)
+		body.SetAngularVelocity(b.AngularVelocity)
+		bodies[b.ID] = body
+	}
+
+	for i, s := range doc.Shapes {
+		body, ok := bodies[s.Body]
+		if !ok {
+			return nil, fmt.Errorf("shape %d: unknown body %d", i, s.Body)
+		}
+		var shape *cp.Shape
+		switch s.Type {
+		case "circle":
+			shape = cp.NewCircle(body, s.Radius, value(s.Offset))
+		case "segment":
+			shape = cp.NewSegment(body, value(s.A), value(s.B), s.Radius)
+		case "poly":
+			if len(s.Verts) < 3 {
+				return nil, fmt.Errorf("shape %d: poly needs at least 3 verts", i)
+			}
+			shape = cp.NewPolyShapeRaw(body, len(s.Verts), s.Verts, s.Radius)
+		default:
+			return nil, fmt.Errorf("shape %d: unknown type %q", i, s.Type)
+		}
+		shape.SetFriction(s.Friction)
+		shape.SetElasticity(s.Elasticity)
+		shape.SetSurfaceV(s.SurfaceVelocity)
+		shape.SetSensor(s.Sensor)
+		shape.SetCollisionType(cp.CollisionType(s.CollisionType))
+		shape.SetFilter(s.Filter)
+		space.AddShape(shape)
+	}
+
+	for i, c := range doc.Constraints {
+		a, okA := bodies[c.A]
+		b, okB := bodies[c.B]
+		if !okA || !okB {
+			return nil, fmt.Errorf("constraint %d: unknown body %d or %d", i, c.A, c.B)
+		}
+		var constraint *cp.Constraint
+		switch c.Type {
+		case "pin":
+			constraint = cp.NewPinJoint(a, b, value(c.AnchorA), value(c.AnchorB))
+			constraint.Class.(*cp.PinJoint).Dist = c.Dist
+		case "slide":
+			constraint = cp.NewSlideJoint(a, b, value(c.AnchorA), value(c.AnchorB), c.Min, c.Max)
+		case "pivot":
+			constraint = cp.NewPivotJoint2(a, b, value(c.AnchorA), value(c.AnchorB))
+		case "groove":
+			constraint = cp.NewGrooveJoint(a, b, value(c.GrooveA), value(c.GrooveB), value(c.AnchorB))
+		case "dampedSpring":
+			constraint = cp.NewDampedSpring(a, b, value(c.AnchorA), value(c.AnchorB), c.RestLength, c.Stiffness, c.Damping)
+		case "dampedRotarySpring":
+			constraint = cp.NewDampedRotarySpring(a, b, c.RestAngle, c.Stiffness, c.Damping)
+		case "rotaryLimit":
+			constraint = cp.NewRotaryLimitJoint(a, b, c.Min, c.Max)
+		case "ratchet":
+			constraint = cp.NewRatchetJoint(a, b, c.Phase, c.Ratchet)
+			constraint.Class.(*cp.RatchetJoint).Angle = c.Angle
+		case "gear":
+			constraint = cp.NewGearJoint(a, b, c.Phase, c.Ratio)
+		case "simpleMotor":
+			constraint = cp.NewSimpleMotor(a, b, c.Rate)
+		default:
+			return nil, fmt.Errorf("constraint %d: unknown type %q", i, c.Type)
+		}
+		constraint.SetMaxForce(c.MaxForce)
+		constraint.SetMaxBias(c.MaxBias)
+		constraint.SetErrorBias(c.ErrorBias)
+		constraint.SetCollideBodies(c.CollideBodies)
+		space.AddConstraint(constraint)
+	}
+
+	return space, nil
+}
+
+func vector(v cp.Vector) *cp.Vector {
+	return &v
+}
+
+func value(v *cp.Vector) cp.Vector {
+	if v == nil {
+		return cp.Vector{}
+	}
+	return *v
+}
+
+// unexported reads a field that cp doesn't provide a getter for. The value is invalid if cp
+// has no such field.
+func unexported(v interface{}, name string) reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(v)).FieldByName(name)
+}
+
+// fields reads fields that cp doesn't provide getters for. Any release of cp could rename
+// them, so rather than panic on a field that is missing or has changed type, fields keeps
+// the first one as err and reads zero for it.
+type fields struct {
+	err error
+}
+
+func (f *fields) field(v interface{}, name string, ok func(reflect.Value) bool) (reflect.Value, bool) {
+	field := unexported(v, name)
+	if field.IsValid() && ok(field) {
+		return field, true
+	}
+	if f.err == nil {
+		f.err = fmt.Errorf("can't read %T.%s, which this version of cp doesn't have", v, name)
+	}
+	return field, false
+}
+
+func (f *fields) float(v interface{}, name string) float64 {
+	if field, ok := f.field(v, name, reflect.Value.CanFloat); ok {
+		return field.Float()
+	}
+	return 0
+}
+
+func (f *fields) uint(v interface{}, name string) uint64 {
+	if field, ok := f.field(v, name, reflect.Value.CanUint); ok {
+		return field.Uint()
+	}
+	return 0
+}
+
+func (f *fields) bool(v interface{}, name string) bool {
+	isBool := func(field reflect.Value) bool { return field.Kind() == reflect.Bool }
+	if field, ok := f.field(v, name, isBool); ok {
+		return field.Bool()
+	}
+	return false
+}
+
+func (f *fields) pointer(v interface{}, name string) uintptr {
+	isPointer := func(field reflect.Value) bool { return field.Kind() == reflect.Ptr }
+	if field, ok := f.field(v, name, isPointer); ok {
+		return field.Pointer()
+	}
+	return 0
+}
+
+func (f *fields) vector(v interface{}, name string) cp.Vector {
+	isVector := func(field reflect.Value) bool { return field.Type() == reflect.TypeOf(cp.Vector{}) }
+	if field, ok := f.field(v, name, isVector); ok {
+		return cp.Vector{X: field.Field(0).Float(), Y: field.Field(1).Float()}
+	}
+	return cp.Vector{}
+}
@@ -0,0 +1,151 @@
+package cpebiten
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// everythingSpace has every kind of body, shape and constraint SaveSpace can write.
+func everythingSpace() *cp.Space {
+	space := cp.NewSpace()
+	space.Iterations = 20
+	space.SetGravity(cp.Vector{X: 0, Y: 300})
+	space.SetDamping(0.9)
+	space.SleepTimeThreshold = 1
+	space.SetCollisionSlop(0.5)
+
+	AddWall(space, space.StaticBody, cp.Vector{X: 0, Y: 400}, cp.Vector{X: 600, Y: 400}, 2)
+	a := AddBox(space, cp.Vector{X: 100, Y: 100}, 1, 30, 20).Body()
+	b := AddCircle(space, cp.Vector{X: 200, Y: 100}, 2, 15).Body()
+	c := AddSegment(space, cp.Vector{X: 300, Y: 100}, 3, 10, 40).Body()
+	a.SetVelocity(10, -5)
+	b.SetAngle(1)
+	c.SetAngularVelocity(2)
+
+	// a static body that isn't in the space, like the scale in contactgraph
+	detached := cp.NewStaticBody()
+	AddWall(space, detached, cp.Vector{X: 50, Y: 300}, cp.Vector{X: 200, Y: 300}, 4)
+
+	kinematic := space.AddBody(cp.NewKinematicBody())
+	kinematic.SetPosition(cp.Vector{X: 400, Y: 200})
+	rounded := space.AddShape(cp.NewPolyShapeRaw(kinematic, 3, []cp.Vector{{X: 0, Y: 0}, {X: 20, Y: 0}, {X: 10, Y: 15}}, 3))
+	rounded.SetSensor(true)
+	rounded.SetCollisionType(7)
+	rounded.SetSurfaceV(cp.Vector{X: 5, Y: 0})
+	rounded.SetFilter(cp.NewShapeFilter(2, 4, 8))
+	offset := space.AddShape(cp.NewCircle(kinematic, 5, cp.Vector{X: 30, Y: 0}))
+	offset.SetFriction(0.3)
+	offset.SetElasticity(0.8)
+
+	pin := space.AddConstraint(cp.NewPinJoint(a, b, cp.Vector{X: 1, Y: 2}, cp.Vector{X: 3, Y: 4}))
+	pin.SetMaxForce(1000)
+	pin.SetMaxBias(50)
+	pin.SetErrorBias(0.5)
+	pin.SetCollideBodies(false)
+	space.AddConstraint(cp.NewSlideJoint(a, c, cp.Vector{}, cp.Vector{X: 0, Y: 5}, 10, 20))
+	space.AddConstraint(cp.NewPivotJoint2(b, c, cp.Vector{X: 1, Y: 0}, cp.Vector{X: 0, Y: 1}))
+	space.AddConstraint(cp.NewGrooveJoint(space.StaticBody, a, cp.Vector{X: 0, Y: 50}, cp.Vector{X: 200, Y: 50}, cp.Vector{}))
+	space.AddConstraint(cp.NewDampedSpring(b, kinematic, cp.Vector{}, cp.Vector{}, 100, 10, 0.5))
+	space.AddConstraint(cp.NewDampedRotarySpring(a, b, 0.5, 20, 1))
+	space.AddConstraint(cp.NewRotaryLimitJoint(b, c, -1, 1))
+	space.AddConstraint(cp.NewRatchetJoint(a, c, 0.25, 0.5))
+	space.AddConstraint(cp.NewGearJoint(b, c, 0.1, 2))
+	space.AddConstraint(cp.NewSimpleMotor(a, kinematic, 3))
+	return space
+}
+
+func TestSaveLoadSpace(t *testing.T) {
+	var saved bytes.Buffer
+	if err := SaveSpace(&saved, everythingSpace()); err != nil {
+		t.Fatal(err)
+	}
+	space, err := LoadSpace(bytes.NewReader(saved.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again bytes.Buffer
+	if err := SaveSpace(&again, space); err != nil {
+		t.Fatal(err)
+	}
+	if saved.String() != again.String() {
+		t.Errorf("saving the loaded space wrote\n%s\nbut the original was\n%s", again.String(), saved.String())
+	}
+
+	doc, err := MarshalSpace(space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Bodies) != 6 || len(doc.Shapes) != 7 || len(doc.Constraints) != 10 {
+		t.Errorf("got %d bodies, %d shapes and %d constraints, want 6, 7 and 10", len(doc.Bodies), len(doc.Shapes), len(doc.Constraints))
+	}
+}
+
+func TestLoadSpaceErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"version", `{"version": 2}`, "unsupported space format version 2"},
+		{"body type", `{"version": 1, "bodies": [{"id": 1, "type": "wobbly"}]}`, `unknown type "wobbly"`},
+		{"duplicate body", `{"version": 1, "bodies": [{"id": 1, "type": "kinematic"}, {"id": 1, "type": "kinematic"}]}`, "duplicate body id 1"},
+		{"shape body", `{"version": 1, "shapes": [{"body": 3, "type": "circle"}]}`, "unknown body 3"},
+		{"poly", `{"version": 1, "shapes": [{"body": 0, "type": "poly", "verts": [{"X": 0, "Y": 0}]}]}`, "at least 3 verts"},
+		{"constraint type", `{"version": 1, "constraints": [{"type": "glue"}]}`, `unknown type "glue"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadSpace(strings.NewReader(test.json))
+			if err == nil {
+				t.Fatal("loaded without an error")
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %q, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	type renamed struct {
+		slop      float64
+		kind      string
+		surfaceV  cp.Vector
+		sensor    bool
+		collision uint32
+	}
+	v := &renamed{slop: 0.5, surfaceV: cp.Vector{X: 1, Y: 2}, sensor: true, collision: 3}
+
+	var f fields
+	if f.float(v, "slop") != 0.5 || f.vector(v, "surfaceV") != (cp.Vector{X: 1, Y: 2}) || !f.bool(v, "sensor") || f.uint(v, "collision") != 3 {
+		t.Error("read the wrong values")
+	}
+	if f.err != nil {
+		t.Fatal(f.err)
+	}
+
+	tests := []struct {
+		name string
+		read func(f *fields)
+	}{
+		{"missing", func(f *fields) { f.float(v, "collisionSlop") }},
+		{"not a float", func(f *fields) { f.float(v, "kind") }},
+		{"not a uint", func(f *fields) { f.uint(v, "slop") }},
+		{"not a bool", func(f *fields) { f.bool(v, "slop") }},
+		{"not a pointer", func(f *fields) { f.pointer(v, "slop") }},
+		{"not a vector", func(f *fields) { f.vector(v, "slop") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var f fields
+			test.read(&f)
+			if f.err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !headless
+
 package cpebiten
 
 import "github.com/hajimehoshi/ebiten/v2"
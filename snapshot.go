@@ -0,0 +1,304 @@
+package cpebiten
+
+import (
+	"reflect"
+	"sort"
+	"unsafe"
+
+	"github.com/jakecoffman/cp"
+)
+
+// BodyState is the dynamic state of a single body at the time of a snapshot.
+type BodyState struct {
+	Body            *cp.Body
+	Position        cp.Vector
+	Velocity        cp.Vector
+	Angle           float64
+	AngularVelocity float64
+	Sleeping        bool
+}
+
+// ConstraintState records a constraint that was in the space and the impulse it had
+// accumulated, which the next step starts solving it from.
+type ConstraintState struct {
+	Constraint *cp.Constraint
+	// Impulse is a vector for pivot and groove joints. The other kinds of constraint
+	// accumulate a single number, which is kept in X.
+	Impulse cp.Vector
+}
+
+// Snapshot is the state of a space at the end of a physics tick.
+type Snapshot struct {
+	Tick        uint64
+	Bodies      []BodyState
+	Constraints []ConstraintState
+}
+
+// TakeSnapshot captures the state of every non-static body and every constraint in the space.
+// Constraints attached to any of the ignore bodies, such as the invisible body used for
+// grabbing with the mouse, are left out.
+func TakeSnapshot(space *cp.Space, tick uint64, ignore ...*cp.Body) *Snapshot {
+	s := &Snapshot{Tick: tick}
+
+	space.EachBody(func(body *cp.Body) {
+		if body.GetType() == cp.BODY_STATIC {
+			return
+		}
+		s.Bodies = append(s.Bodies, BodyState{
+			Body:            body,
+			Position:        body.Position(),
+			Velocity:        body.Velocity(),
+			Angle:           body.Angle(),
+			AngularVelocity: body.AngularVelocity(),
+			Sleeping:        body.IsSleeping(),
+		})
+	})
+
+	skip := ignoredConstraints(ignore)
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		if skip[constraint] {
+			return
+		}
+		s.Constraints = append(s.Constraints, ConstraintState{
+			Constraint: constraint,
+			Impulse:    getImpulse(constraint),
+		})
+	})
+
+	return s
+}
+
+// Restore puts the bodies back where they were and re-adds or removes constraints so the set
+// in the space matches the snapshot, which brings back joints that broke after it was taken,
+// and gives them back the impulses they had. Bodies that have since been removed from the
+// space are skipped.
+//
+// Setting a body's state wakes it up, so bodies that were asleep in the snapshot and still
+// are, where they were, are left alone to keep them asleep. cp has no way to put a body to
+// sleep from outside a step, so ones that have woken up since stay awake until the space puts
+// them back to sleep.
+func (s *Snapshot) Restore(space *cp.Space, ignore ...*cp.Body) {
+	for _, state := range s.Bodies {
+		body := state.Body
+		if !space.ContainsBody(body) {
+			continue
+		}
+		if state.Sleeping && body.IsSleeping() && state.unchanged(body) {
+			continue
+		}
+		body.SetAngle(state.Angle)
+		body.SetPosition(state.Position)
+		body.SetVelocityVector(state.Velocity)
+		body.SetAngularVelocity(state.AngularVelocity)
+		body.EachShape(func(shape *cp.Shape) {
+			shape.CacheBB()
+		})
+	}
+
+	keep := map[*cp.Constraint]bool{}
+	for _, state := range s.Constraints {
+		keep[state.Constraint] = true
+		if !space.ContainsConstraint(state.Constraint) {
+			space.AddConstraint(state.Constraint)
+		}
+		setImpulse(state.Constraint, state.Impulse)
+	}
+
+	skip := ignoredConstraints(ignore)
+	var extra []*cp.Constraint
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		if !keep[constraint] && !skip[constraint] {
+			extra = append(extra, constraint)
+		}
+	})
+	for _, constraint := range extra {
+		space.RemoveConstraint(constraint)
+	}
+}
+
+// unchanged reports whether the body is still in the recorded state.
+func (state BodyState) unchanged(body *cp.Body) bool {
+	return body.Position() == state.Position && body.Velocity() == state.Velocity &&
+		body.Angle() == state.Angle && body.AngularVelocity() == state.AngularVelocity
+}
+
+func ignoredConstraints(bodies []*cp.Body) map[*cp.Constraint]bool {
+	skip := map[*cp.Constraint]bool{}
+	for _, body := range bodies {
+		body.EachConstraint(func(constraint *cp.Constraint) {
+			skip[constraint] = true
+		})
+	}
+	return skip
+}
+
+// History is a ring buffer of the most recent snapshots, oldest first.
+type History struct {
+	snapshots []*Snapshot
+	start     int
+	count     int
+}
+
+// NewHistory creates a history that holds up to capacity snapshots.
+func NewHistory(capacity int) *History {
+	return &History{
+		snapshots: make([]*Snapshot, capacity),
+	}
+}
+
+// Len returns the number of snapshots held.
+func (h *History) Len() int {
+	return h.count
+}
+
+// Push adds a snapshot, evicting the oldest one when the buffer is full. Any snapshots at or
+// after the new snapshot's tick are dropped first, since after a rewind they describe a
+// future that will no longer happen.
+func (h *History) Push(s *Snapshot) {
+	for h.count > 0 && h.at(h.count-1).Tick >= s.Tick {
+		h.count--
+	}
+	if len(h.snapshots) == 0 {
+		return
+	}
+	if h.count == len(h.snapshots) {
+		h.start = (h.start + 1) % len(h.snapshots)
+		h.count--
+	}
+	h.snapshots[(h.start+h.count)%len(h.snapshots)] = s
+	h.count++
+}
+
+// Clear drops every snapshot.
+func (h *History) Clear() {
+	h.start, h.count = 0, 0
+}
+
+// Get returns the snapshot taken at tick, if it is still in the buffer.
+func (h *History) Get(tick uint64) (*Snapshot, bool) {
+	if h.count == 0 {
+		return nil, false
+	}
+	first := h.at(0).Tick
+	if tick < first || tick-first >= uint64(h.count) {
+		return nil, false
+	}
+	s := h.at(int(tick - first))
+	return s, s.Tick == tick
+}
+
+// Oldest returns the earliest snapshot in the buffer, or nil if it is empty.
+func (h *History) Oldest() *Snapshot {
+	if h.count == 0 {
+		return nil
+	}
+	return h.at(0)
+}
+
+// Latest returns the most recent snapshot in the buffer, or nil if it is empty.
+func (h *History) Latest() *Snapshot {
+	if h.count == 0 {
+		return nil
+	}
+	return h.at(h.count - 1)
+}
+
+func (h *History) at(i int) *Snapshot {
+	return h.snapshots[(h.start+i)%len(h.snapshots)]
+}
+
+// impulseFields names the field each kind of constraint accumulates its impulse in. Every step
+// starts from the impulse the one before ended with, so it is part of the state of the space.
+var impulseFields = map[reflect.Type]string{
+	reflect.TypeOf(&cp.PinJoint{}):           "jnAcc",
+	reflect.TypeOf(&cp.SlideJoint{}):         "jnAcc",
+	reflect.TypeOf(&cp.PivotJoint{}):         "jAcc",
+	reflect.TypeOf(&cp.GrooveJoint{}):        "jAcc",
+	reflect.TypeOf(&cp.DampedSpring{}):       "jAcc",
+	reflect.TypeOf(&cp.DampedRotarySpring{}): "jAcc",
+	reflect.TypeOf(&cp.RotaryLimitJoint{}):   "jAcc",
+	reflect.TypeOf(&cp.RatchetJoint{}):       "jAcc",
+	reflect.TypeOf(&cp.GearJoint{}):          "jAcc",
+	reflect.TypeOf(&cp.SimpleMotor{}):        "jAcc",
+}
+
+// impulse returns the accumulated impulse of the constraint, a float64 or a cp.Vector
+// depending on the kind of constraint, as a value that can be set even though cp doesn't
+// export it. It is invalid for a kind of constraint cp didn't have when this was written.
+func impulse(constraint *cp.Constraint) reflect.Value {
+	name, ok := impulseFields[reflect.TypeOf(constraint.Class)]
+	if !ok {
+		return reflect.Value{}
+	}
+	return settable(constraint.Class, name)
+}
+
+// settable returns a field that cp doesn't export as a value that can be set, or an invalid
+// value if there is no such field.
+func settable(v interface{}, name string) reflect.Value {
+	field := unexported(v, name)
+	if !field.IsValid() {
+		return field
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+func getImpulse(constraint *cp.Constraint) cp.Vector {
+	j := impulse(constraint)
+	if !j.IsValid() {
+		return cp.Vector{}
+	}
+	switch j := j.Interface().(type) {
+	case float64:
+		return cp.Vector{X: j}
+	case cp.Vector:
+		return j
+	}
+	return cp.Vector{}
+}
+
+func setImpulse(constraint *cp.Constraint, v cp.Vector) {
+	j := impulse(constraint)
+	switch {
+	case !j.IsValid():
+	case j.Kind() == reflect.Float64:
+		j.SetFloat(v.X)
+	case j.Type() == reflect.TypeOf(v):
+		j.Set(reflect.ValueOf(v))
+	}
+}
+
+// resetWarmStart makes the space forget what it carries over from one step to the next: the
+// contacts between shapes, which are dropped by taking every shape out of the space and
+// putting them back in order, the impulses of the constraints and the velocity the solver
+// pushes overlapping bodies apart with. Two spaces holding the same scene step identically
+// after it, whatever happened to them before.
+func resetWarmStart(space *cp.Space) {
+	var shapes []*cp.Shape
+	space.EachShape(func(shape *cp.Shape) {
+		shapes = append(shapes, shape)
+	})
+	sort.Slice(shapes, func(i, j int) bool {
+		return shapes[i].HashId() < shapes[j].HashId()
+	})
+	for _, shape := range shapes {
+		space.RemoveShape(shape)
+	}
+	for _, shape := range shapes {
+		space.AddShape(shape)
+	}
+
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		zero(impulse(constraint))
+	})
+	space.EachBody(func(body *cp.Body) {
+		zero(settable(body, "v_bias"))
+		zero(settable(body, "w_bias"))
+	})
+}
+
+func zero(v reflect.Value) {
+	if v.IsValid() {
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
@@ -0,0 +1,172 @@
+package cpebiten
+
+import (
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func pinJoint(space *cp.Space) *cp.Constraint {
+	var pin *cp.Constraint
+	space.EachConstraint(func(constraint *cp.Constraint) {
+		if _, ok := constraint.Class.(*cp.PinJoint); ok {
+			pin = constraint
+		}
+	})
+	return pin
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	game := boxesGame()
+	pin := pinJoint(game.Space)
+	NewRunner(game).Run(30)
+	snapshot := TakeSnapshot(game.Space, game.Ticks)
+	want := marshal(t, game)
+
+	if len(snapshot.Bodies) != 3 {
+		t.Errorf("got %d bodies, want the 3 boxes", len(snapshot.Bodies))
+	}
+	if len(snapshot.Constraints) != 1 || snapshot.Constraints[0].Constraint != pin {
+		t.Fatalf("got constraints %v, want the pin joint", snapshot.Constraints)
+	}
+	impulse := snapshot.Constraints[0].Impulse
+	if impulse.X == 0 {
+		t.Error("the pin joint holding up a box has no impulse")
+	}
+
+	NewRunner(game).Run(30)
+	game.Space.RemoveConstraint(pin)
+	snapshot.Restore(game.Space)
+
+	if got := marshal(t, game); got != want {
+		t.Errorf("restored\n%s\nbut took\n%s", got, want)
+	}
+	if !game.Space.ContainsConstraint(pin) {
+		t.Error("the broken pin joint wasn't put back")
+	}
+	if got := TakeSnapshot(game.Space, game.Ticks).Constraints[0].Impulse; got != impulse {
+		t.Errorf("restored an impulse of %v, want %v", got, impulse)
+	}
+}
+
+func TestSnapshotIgnore(t *testing.T) {
+	game := boxesGame()
+	mouse := cp.NewKinematicBody()
+	var box *cp.Body
+	game.Space.EachBody(func(body *cp.Body) {
+		box = body
+	})
+	grab := game.Space.AddConstraint(cp.NewPivotJoint2(mouse, box, cp.Vector{}, cp.Vector{}))
+
+	snapshot := TakeSnapshot(game.Space, 0, mouse)
+	if len(snapshot.Constraints) != 1 {
+		t.Fatalf("got %d constraints, want only the pin joint", len(snapshot.Constraints))
+	}
+	snapshot.Restore(game.Space, mouse)
+	if !game.Space.ContainsConstraint(grab) {
+		t.Error("restoring let go of the grab")
+	}
+}
+
+func TestSnapshotSleeping(t *testing.T) {
+	space := cp.NewSpace()
+	space.SetGravity(cp.Vector{X: 0, Y: 100})
+	space.SleepTimeThreshold = 0.5
+	AddWall(space, space.StaticBody, cp.Vector{X: 0, Y: 100}, cp.Vector{X: 200, Y: 100}, 0)
+	box := AddBox(space, cp.Vector{X: 100, Y: 90}, 1, 20, 20).Body()
+	game := NewGame(space, 60)
+
+	ticks, asleep := NewRunner(game).RunUntil(func(*Game) bool { return box.IsSleeping() }, 600)
+	if !asleep {
+		t.Fatalf("the box is still awake after %d ticks", ticks)
+	}
+	snapshot := TakeSnapshot(space, game.Ticks)
+	if !snapshot.Bodies[0].Sleeping {
+		t.Error("the snapshot has the box awake")
+	}
+	snapshot.Restore(space)
+	if !box.IsSleeping() {
+		t.Error("restoring woke the box up")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(4)
+	if h.Oldest() != nil || h.Latest() != nil {
+		t.Error("an empty history has snapshots")
+	}
+	for tick := uint64(0); tick < 6; tick++ {
+		h.Push(&Snapshot{Tick: tick})
+	}
+	if h.Len() != 4 || h.Oldest().Tick != 2 || h.Latest().Tick != 5 {
+		t.Errorf("got %d snapshots from %d to %d, want 4 from 2 to 5", h.Len(), h.Oldest().Tick, h.Latest().Tick)
+	}
+	if _, ok := h.Get(1); ok {
+		t.Error("got a snapshot that was evicted")
+	}
+	if s, ok := h.Get(3); !ok || s.Tick != 3 {
+		t.Errorf("got %v, %v for tick 3", s, ok)
+	}
+
+	// pushing an earlier tick, as after a rewind, drops the ones after it
+	h.Push(&Snapshot{Tick: 3})
+	if h.Len() != 2 || h.Latest().Tick != 3 {
+		t.Errorf("got %d snapshots up to %d, want 2 up to 3", h.Len(), h.Latest().Tick)
+	}
+	if _, ok := h.Get(4); ok {
+		t.Error("got a snapshot from after the rewind")
+	}
+
+	h.Clear()
+	if h.Len() != 0 || h.Latest() != nil {
+		t.Error("Clear left snapshots behind")
+	}
+}
+
+func TestRewind(t *testing.T) {
+	game := boxesGame()
+	if game.Rewind(0) {
+		t.Error("rewound without a history")
+	}
+
+	game.History = NewHistory(10)
+	states := []string{marshal(t, game)}
+	runner := NewRunner(game)
+	runner.AfterTick = func(uint64) {
+		states = append(states, marshal(t, game))
+	}
+	runner.Run(8)
+
+	if game.Rewind(9) {
+		t.Error("rewound to before the start")
+	}
+	if !game.Rewind(3) {
+		t.Fatal("couldn't rewind 3 ticks")
+	}
+	if game.Ticks != 5 || !game.Paused {
+		t.Errorf("got tick %d and paused %v, want tick 5 and paused", game.Ticks, game.Paused)
+	}
+	if got := marshal(t, game); got != states[5] {
+		t.Errorf("rewound to\n%s\nwant\n%s", got, states[5])
+	}
+
+	// all the way back to where the game started
+	if !game.Rewind(5) {
+		t.Fatal("couldn't rewind to the start")
+	}
+	if got := marshal(t, game); got != states[0] {
+		t.Errorf("rewound to\n%s\nwant\n%s", got, states[0])
+	}
+
+	// stepping again replaces the future that was rewound away
+	runner.Run(1)
+	if _, ok := game.History.Get(2); ok {
+		t.Error("the history kept a tick from before the rewind")
+	}
+
+	// the history only reaches back so far
+	runner.Run(20)
+	if game.Rewind(15) {
+		t.Error("rewound further than the history holds")
+	}
+}
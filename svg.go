@@ -0,0 +1,227 @@
+package cpebiten
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten/raster"
+)
+
+// SVG is a cp.Drawer that records a space's debug drawing as an SVG document, for diagrams
+// that stay sharp at any size. It draws the same shapes as DrawOptions with the same theme
+// colors, using circles, capsules and rounded polygons instead of triangles.
+type SVG struct {
+	width, height int
+	view          raster.View
+	pixel         float64
+	flags         uint
+	theme         Theme
+	background    cp.FColor
+
+	body bytes.Buffer
+}
+
+// NewSVG creates an SVG drawer for a document of the given size, in pixels.
+func NewSVG(width, height int) *SVG {
+	return NewSVGWithView(width, height, raster.View{})
+}
+
+// NewSVGWithView creates an SVG drawer that transforms everything by view, like
+// raster.NewRasterizerView. Outlines stay one pixel wide at any zoom.
+func NewSVGWithView(width, height int, view raster.View) *SVG {
+	return &SVG{
+		width:  width,
+		height: height,
+		view:   view,
+		pixel:  view.PixelSize(),
+		flags:  DrawAll,
+		theme:  HashTheme,
+	}
+}
+
+// SetTheme chooses the colors things are drawn with. The default is HashTheme.
+func (s *SVG) SetTheme(theme Theme) {
+	s.theme = theme
+}
+
+// SetFlags chooses what DrawSpace draws. The default is DrawAll.
+func (s *SVG) SetFlags(flags uint) {
+	s.flags = flags
+}
+
+// SetBackground fills the document with a color. The default is transparent.
+func (s *SVG) SetBackground(color cp.FColor) {
+	s.background = color
+}
+
+// WriteTo writes the document with everything drawn so far.
+func (s *SVG) WriteTo(w io.Writer) (int64, error) {
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		s.width, s.height, s.width, s.height)
+	if s.background.A > 0 {
+		fmt.Fprintf(&doc, `<rect width="100%%" height="100%%"%s/>`+"\n", paint("fill", s.background))
+	}
+	if v := s.view; v != (raster.View{}) {
+		fmt.Fprintf(&doc, `<g transform="matrix(%s %s %s %s %s %s)">`+"\n",
+			num(v.A), num(v.C), num(v.B), num(v.D), num(v.TX), num(v.TY))
+	} else {
+		doc.WriteString("<g>\n")
+	}
+	doc.Write(s.body.Bytes())
+	doc.WriteString("</g>\n</svg>\n")
+	return doc.WriteTo(w)
+}
+
+// SaveFile is a convenience for WriteTo that writes to a file.
+func (s *SVG) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err := s.WriteTo(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *SVG) DrawCircle(pos cp.Vector, angle, radius float64, outline, fill cp.FColor, _ interface{}) {
+	fmt.Fprintf(&s.body, `<circle cx="%s" cy="%s" r="%s"%s%s/>`+"\n",
+		num(pos.X), num(pos.Y), num(radius), paint("fill", fill), s.stroke(outline))
+	s.DrawSegment(pos, pos.Add(cp.ForAngle(angle).Mult(radius)), outline, nil)
+}
+
+func (s *SVG) DrawSegment(a, b cp.Vector, fill cp.FColor, data interface{}) {
+	s.DrawFatSegment(a, b, 0, fill, fill, data)
+}
+
+// DrawFatSegment draws a capsule, or a hairline when radius is zero.
+func (s *SVG) DrawFatSegment(a, b cp.Vector, radius float64, outline, fill cp.FColor, _ interface{}) {
+	if radius <= 0 {
+		fmt.Fprintf(&s.body, `<line x1="%s" y1="%s" x2="%s" y2="%s"%s stroke-linecap="round"/>`+"\n",
+			num(a.X), num(a.Y), num(b.X), num(b.Y), s.stroke(outline))
+		return
+	}
+	if a.Equal(b) {
+		s.DrawCircle(a, 0, radius, outline, fill, nil)
+		return
+	}
+
+	n := b.Sub(a).ReversePerp().Normalize().Mult(radius)
+	r := num(radius)
+	p0, p1, p2, p3 := a.Add(n), b.Add(n), b.Sub(n), a.Sub(n)
+	fmt.Fprintf(&s.body, `<path d="M%s %s L%s %s A%s %s 0 0 1 %s %s L%s %s A%s %s 0 0 1 %s %s Z"%s%s/>`+"\n",
+		num(p0.X), num(p0.Y), num(p1.X), num(p1.Y),
+		r, r, num(p2.X), num(p2.Y), num(p3.X), num(p3.Y),
+		r, r, num(p0.X), num(p0.Y),
+		paint("fill", fill), s.stroke(outline))
+}
+
+// DrawPolygon draws the polygon with its corners rounded off by radius. Chipmunk polygons are
+// convex and wound counter-clockwise, so the rounded outline is the edges pushed out along
+// their normals joined by arcs.
+func (s *SVG) DrawPolygon(count int, verts []cp.Vector, radius float64, outline, fill cp.FColor, _ interface{}) {
+	if count == 0 {
+		return
+	}
+	if radius <= 0 {
+		s.body.WriteString(`<polygon points="`)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				s.body.WriteByte(' ')
+			}
+			fmt.Fprintf(&s.body, "%s,%s", num(verts[i].X), num(verts[i].Y))
+		}
+		fmt.Fprintf(&s.body, `"%s%s stroke-linejoin="round"/>`+"\n", paint("fill", fill), s.stroke(outline))
+		return
+	}
+
+	r := num(radius)
+	s.body.WriteString(`<path d="`)
+	for i := 0; i < count; i++ {
+		v := verts[i]
+		prev := verts[(i-1+count)%count]
+		next := verts[(i+1)%count]
+		n1 := v.Sub(prev).ReversePerp().Normalize().Mult(radius)
+		n2 := next.Sub(v).ReversePerp().Normalize().Mult(radius)
+
+		a, b := v.Add(n1), v.Add(n2)
+		if i == 0 {
+			fmt.Fprintf(&s.body, "M%s %s ", num(a.X), num(a.Y))
+		} else {
+			fmt.Fprintf(&s.body, "L%s %s ", num(a.X), num(a.Y))
+		}
+		fmt.Fprintf(&s.body, "A%s %s 0 0 1 %s %s ", r, r, num(b.X), num(b.Y))
+	}
+	fmt.Fprintf(&s.body, `Z"%s%s/>`+"\n", paint("fill", fill), s.stroke(outline))
+}
+
+// DrawDot draws a dot size pixels across.
+func (s *SVG) DrawDot(size float64, pos cp.Vector, fill cp.FColor, _ interface{}) {
+	fmt.Fprintf(&s.body, `<circle cx="%s" cy="%s" r="%s"%s/>`+"\n",
+		num(pos.X), num(pos.Y), num(size*0.5*s.pixel), paint("fill", fill))
+}
+
+func (s *SVG) DrawBB(bb cp.BB, outline cp.FColor) {
+	fmt.Fprintf(&s.body, `<rect x="%s" y="%s" width="%s" height="%s" fill="none"%s/>`+"\n",
+		num(bb.L), num(bb.B), num(bb.R-bb.L), num(bb.T-bb.B), s.stroke(outline))
+}
+
+func (s *SVG) Flags() uint {
+	return s.flags
+}
+
+func (s *SVG) OutlineColor() cp.FColor {
+	return s.theme.OutlineColor()
+}
+
+func (s *SVG) ShapeColor(shape *cp.Shape, _ interface{}) cp.FColor {
+	return s.theme.ShapeColor(shape)
+}
+
+func (s *SVG) ConstraintColor() cp.FColor {
+	return s.theme.ConstraintColor()
+}
+
+func (s *SVG) CollisionPointColor() cp.FColor {
+	return s.theme.CollisionPointColor()
+}
+
+func (s *SVG) Data() interface{} {
+	return nil
+}
+
+// stroke is a one pixel wide outline.
+func (s *SVG) stroke(color cp.FColor) string {
+	return paint("stroke", color) + ` stroke-width="` + num(s.pixel) + `"`
+}
+
+// paint formats a color as a fill or stroke attribute with its opacity.
+func paint(attr string, color cp.FColor) string {
+	c := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, colorByte(color.R), colorByte(color.G), colorByte(color.B))
+	if color.A < 1 {
+		c += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(color.A)))
+	}
+	return c
+}
+
+func colorByte(f float32) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(float64(f)*255))))
+}
+
+// num formats a coordinate with enough precision for a diagram and no trailing zeros.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000+0, 'f', -1, 64)
+}
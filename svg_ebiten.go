@@ -0,0 +1,11 @@
+//go:build !headless
+
+package cpebiten
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// NewSVGView creates an SVG drawer that transforms everything by view, like
+// NewDrawOptionsView. Outlines stay one pixel wide at any zoom.
+func NewSVGView(width, height int, view ebiten.GeoM) *SVG {
+	return NewSVGWithView(width, height, viewOf(view))
+}
@@ -0,0 +1,84 @@
+package cpebiten
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten/raster"
+)
+
+var (
+	svgRed   = cp.FColor{R: 1, A: 1}
+	svgBlack = cp.FColor{A: 1}
+)
+
+func TestSVGShapes(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		draw func(s *SVG)
+		want string
+	}{
+		{
+			name: "capsule",
+			draw: func(s *SVG) {
+				s.DrawFatSegment(cp.Vector{}, cp.Vector{X: 10}, 2, svgBlack, svgRed, nil)
+			},
+			want: `<path d="M0 -2 L10 -2 A2 2 0 0 1 10 2 L0 2 A2 2 0 0 1 0 -2 Z" fill="#ff0000" stroke="#000000" stroke-width="1"/>` + "\n",
+		},
+		{
+			name: "rounded polygon",
+			draw: func(s *SVG) {
+				s.DrawPolygon(4, []cp.Vector{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}, 2, svgBlack, svgRed, nil)
+			},
+			want: `<path d="M-2 0 A2 2 0 0 1 0 -2 L10 -2 A2 2 0 0 1 12 0 L12 10 A2 2 0 0 1 10 12 L0 12 A2 2 0 0 1 -2 10 Z" fill="#ff0000" stroke="#000000" stroke-width="1"/>` + "\n",
+		},
+		{
+			name: "polygon",
+			draw: func(s *SVG) {
+				s.DrawPolygon(3, []cp.Vector{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 5, Y: 5}}, 0, svgBlack, cp.FColor{R: 1, A: .5}, nil)
+			},
+			want: `<polygon points="0,0 10,0 5,5" fill="#ff0000" fill-opacity="0.5" stroke="#000000" stroke-width="1" stroke-linejoin="round"/>` + "\n",
+		},
+		{
+			name: "circle",
+			draw: func(s *SVG) {
+				s.DrawCircle(cp.Vector{X: 10, Y: 20}, 0, 5, svgBlack, svgRed, nil)
+			},
+			want: `<circle cx="10" cy="20" r="5" fill="#ff0000" stroke="#000000" stroke-width="1"/>` + "\n" +
+				`<line x1="10" y1="20" x2="15" y2="20" stroke="#000000" stroke-width="1" stroke-linecap="round"/>` + "\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewSVG(40, 30)
+			test.draw(s)
+			var doc strings.Builder
+			if _, err := s.WriteTo(&doc); err != nil {
+				t.Fatal(err)
+			}
+			want := `<svg xmlns="http://www.w3.org/2000/svg" width="40" height="30" viewBox="0 0 40 30">` + "\n" +
+				"<g>\n" + test.want + "</g>\n</svg>\n"
+			if doc.String() != want {
+				t.Errorf("got\n%s\nwant\n%s", doc.String(), want)
+			}
+		})
+	}
+}
+
+func TestSVGView(t *testing.T) {
+	// zoomed in twice and moved, outlines should still be one pixel wide
+	s := NewSVGWithView(40, 30, raster.View{A: 2, D: 2, TX: 5, TY: 7})
+	s.DrawSegment(cp.Vector{}, cp.Vector{X: 10}, svgBlack, nil)
+	var doc strings.Builder
+	if _, err := s.WriteTo(&doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<g transform="matrix(2 0 0 2 5 7)">`,
+		`<line x1="0" y1="0" x2="10" y2="0" stroke="#000000" stroke-width="0.5" stroke-linecap="round"/>`,
+	} {
+		if !strings.Contains(doc.String(), want) {
+			t.Errorf("missing %s in\n%s", want, doc.String())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package cpebiten
+
+import (
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Theme picks the colors that DrawOptions draws with.
+type Theme interface {
+	OutlineColor() cp.FColor
+	ShapeColor(shape *cp.Shape) cp.FColor
+	ConstraintColor() cp.FColor
+	CollisionPointColor() cp.FColor
+}
+
+var (
+	// HashTheme gives every shape its own color from a hash of its id, like Chipmunk's demos.
+	HashTheme Theme = hashTheme{}
+	// CollisionTypeTheme colors shapes by their collision type, so shapes that share
+	// collision handlers look the same.
+	CollisionTypeTheme Theme = collisionTypeTheme{}
+	// BodyTypeTheme colors shapes by whether their body is dynamic, kinematic or static.
+	BodyTypeTheme Theme = bodyTypeTheme{}
+	// HighContrastTheme uses the Okabe-Ito palette, which stays distinguishable for the
+	// common kinds of color blindness, on a dark background.
+	HighContrastTheme Theme = highContrastTheme{}
+)
+
+// ShapeColorTheme returns a theme that takes its shape colors from f and everything else
+// from base.
+func ShapeColorTheme(base Theme, f func(shape *cp.Shape) cp.FColor) Theme {
+	return shapeColorTheme{base, f}
+}
+
+type shapeColorTheme struct {
+	Theme
+	f func(shape *cp.Shape) cp.FColor
+}
+
+func (t shapeColorTheme) ShapeColor(shape *cp.Shape) cp.FColor {
+	return t.f(shape)
+}
+
+// defaultColors are the outline, constraint and collision point colors shared by most themes.
+type defaultColors struct{}
+
+func (defaultColors) OutlineColor() cp.FColor {
+	return cp.FColor{200.0 / 255.0, 210.0 / 255.0, 230.0 / 255.0, 1}
+}
+
+func (defaultColors) ConstraintColor() cp.FColor {
+	return cp.FColor{0, 0.75, 0, 1}
+}
+
+func (defaultColors) CollisionPointColor() cp.FColor {
+	return cp.FColor{1, 0, 0, 1}
+}
+
+// stateColor returns the color for sensors, sleeping and idle bodies, which every theme
+// except the high contrast one shows the same way.
+func stateColor(shape *cp.Shape) (cp.FColor, bool) {
+	if shape.Sensor() {
+		return cp.FColor{R: 1, G: 1, B: 1, A: .1}, true
+	}
+
+	body := shape.Body()
+
+	if body.IsSleeping() {
+		return cp.FColor{R: .2, G: .2, B: .2, A: 1}, true
+	}
+
+	if space := shape.Space(); space != nil && body.IdleTime() > space.SleepTimeThreshold {
+		return cp.FColor{R: .66, G: .66, B: .66, A: 1}, true
+	}
+
+	return cp.FColor{}, false
+}
+
+type hashTheme struct {
+	defaultColors
+}
+
+func (hashTheme) ShapeColor(shape *cp.Shape) cp.FColor {
+	if color, ok := stateColor(shape); ok {
+		return color
+	}
+
+	val := shape.HashId()
+
+	// scramble the bits up using Robert Jenkins' 32 bit integer hash function
+	val = (val + 0x7ed55d16) + (val << 12)
+	val = (val ^ 0xc761c23c) ^ (val >> 19)
+	val = (val + 0x165667b1) + (val << 5)
+	val = (val + 0xd3a2646c) ^ (val << 9)
+	val = (val + 0xfd7046c5) + (val << 3)
+	val = (val ^ 0xb55a4f09) ^ (val >> 16)
+
+	r := float32((val >> 0) & 0xFF)
+	g := float32((val >> 8) & 0xFF)
+	b := float32((val >> 16) & 0xFF)
+
+	max := float32(math.Max(math.Max(float64(r), float64(g)), float64(b)))
+	min := float32(math.Min(math.Min(float64(r), float64(g)), float64(b)))
+	var intensity float32
+	if shape.Body().GetType() == cp.BODY_STATIC {
+		intensity = 0.15
+	} else {
+		intensity = 0.75
+	}
+
+	if min == max {
+		return cp.FColor{R: intensity, A: 1}
+	}
+
+	var coef = intensity / (max - min)
+	return cp.FColor{
+		R: (r - min) * coef,
+		G: (g - min) * coef,
+		B: (b - min) * coef,
+		A: 1,
+	}
+}
+
+// collisionTypeColors is indexed by collision type, wrapping around for large types.
+var collisionTypeColors = []cp.FColor{
+	{R: .4, G: .4, B: .4, A: 1},
+	{R: .85, G: .35, B: .25, A: 1},
+	{R: .25, G: .6, B: .85, A: 1},
+	{R: .35, G: .75, B: .3, A: 1},
+	{R: .9, G: .75, B: .2, A: 1},
+	{R: .65, G: .4, B: .8, A: 1},
+	{R: .2, G: .75, B: .7, A: 1},
+	{R: .9, G: .5, B: .7, A: 1},
+}
+
+type collisionTypeTheme struct {
+	defaultColors
+}
+
+func (collisionTypeTheme) ShapeColor(shape *cp.Shape) cp.FColor {
+	if color, ok := stateColor(shape); ok {
+		return color
+	}
+	// shapes are drawn in the default color if cp has renamed the field
+	var f fields
+	collisionType := f.uint(shape, "collisionType")
+	if collisionType == 0 {
+		return collisionTypeColors[0]
+	}
+	return collisionTypeColors[1+(collisionType-1)%uint64(len(collisionTypeColors)-1)]
+}
+
+type bodyTypeTheme struct {
+	defaultColors
+}
+
+func (bodyTypeTheme) ShapeColor(shape *cp.Shape) cp.FColor {
+	if color, ok := stateColor(shape); ok {
+		return color
+	}
+	switch shape.Body().GetType() {
+	case cp.BODY_KINEMATIC:
+		return cp.FColor{R: .25, G: .55, B: .85, A: 1}
+	case cp.BODY_STATIC:
+		return cp.FColor{R: .3, G: .3, B: .3, A: 1}
+	default:
+		return cp.FColor{R: .85, G: .55, B: .2, A: 1}
+	}
+}
+
+// okabeIto is the Okabe-Ito colorblind-safe palette, minus black.
+var okabeIto = []cp.FColor{
+	{R: 230.0 / 255.0, G: 159.0 / 255.0, B: 0, A: 1},
+	{R: 86.0 / 255.0, G: 180.0 / 255.0, B: 233.0 / 255.0, A: 1},
+	{R: 0, G: 158.0 / 255.0, B: 115.0 / 255.0, A: 1},
+	{R: 240.0 / 255.0, G: 228.0 / 255.0, B: 66.0 / 255.0, A: 1},
+	{R: 0, G: 114.0 / 255.0, B: 178.0 / 255.0, A: 1},
+	{R: 213.0 / 255.0, G: 94.0 / 255.0, B: 0, A: 1},
+	{R: 204.0 / 255.0, G: 121.0 / 255.0, B: 167.0 / 255.0, A: 1},
+}
+
+type highContrastTheme struct{}
+
+func (highContrastTheme) OutlineColor() cp.FColor {
+	return cp.FColor{R: 1, G: 1, B: 1, A: 1}
+}
+
+func (highContrastTheme) ShapeColor(shape *cp.Shape) cp.FColor {
+	if shape.Sensor() {
+		return cp.FColor{R: 1, G: 1, B: 1, A: .2}
+	}
+	body := shape.Body()
+	if body.GetType() == cp.BODY_STATIC {
+		return cp.FColor{R: .5, G: .5, B: .5, A: 1}
+	}
+	if body.IsSleeping() {
+		return cp.FColor{R: .15, G: .15, B: .15, A: 1}
+	}
+	return okabeIto[uint64(shape.HashId())%uint64(len(okabeIto))]
+}
+
+func (highContrastTheme) ConstraintColor() cp.FColor {
+	// bluish green
+	return okabeIto[2]
+}
+
+func (highContrastTheme) CollisionPointColor() cp.FColor {
+	// vermillion
+	return okabeIto[5]
+}
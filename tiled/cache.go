@@ -0,0 +1,97 @@
+//go:build !headless
+
+package tiled
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// blockSize is how many tiles wide and high the blocks of a tile layer drawn ahead of
+	// time are.
+	blockSize = 16
+	// blockLifetime is how many frames a block is kept for after it was last on screen.
+	blockLifetime = 60
+)
+
+// block is part of a tile layer drawn ahead of time.
+type block struct {
+	// image has the tiles of the block that don't change, with room at the top and right for
+	// tiles that stick out of their cell. It is nil if there are none.
+	image *ebiten.Image
+	// animated are the tiles of the block that have to be drawn every frame.
+	animated []placedTile
+	// seen is the last frame the block was on screen.
+	seen int
+}
+
+type blockKey struct {
+	layer *Layer
+	x, y  int
+}
+
+type placedTile struct {
+	x, y int
+	gid  uint32
+}
+
+// block returns a block of a tile layer, drawing it if it isn't kept already.
+func (r *Renderer) block(layer *Layer, bx, by int) *block {
+	key := blockKey{layer, bx, by}
+	b, ok := r.blocks[key]
+	if !ok {
+		b = r.drawBlock(layer, bx, by)
+		r.blocks[key] = b
+	}
+	b.seen = r.frame
+	return b
+}
+
+func (r *Renderer) drawBlock(layer *Layer, bx, by int) *block {
+	b := &block{}
+	op := &ebiten.DrawImageOptions{}
+	for y := by * blockSize; y < (by+1)*blockSize; y++ {
+		for x := bx * blockSize; x < (bx+1)*blockSize; x++ {
+			gid := layer.TileAt(x, y)
+			id, _ := SplitGID(gid)
+			if id == 0 {
+				continue
+			}
+			if _, ok := r.animations[id]; ok {
+				b.animated = append(b.animated, placedTile{x, y, gid})
+				continue
+			}
+			img := r.tiles[id]
+			if img == nil {
+				continue
+			}
+
+			if b.image == nil {
+				b.image = ebiten.NewImage(blockSize*r.Map.TileWidth+r.overhangX, blockSize*r.Map.TileHeight+r.overhangY)
+			}
+			// the block's image starts overhangY above its top row
+			op.GeoM = r.placeTile(img, gid, x-bx*blockSize, y-by*blockSize)
+			op.GeoM.Translate(0, float64(r.overhangY))
+			b.image.DrawImage(img, op)
+		}
+	}
+	return b
+}
+
+// forget lets go of the blocks that haven't been on screen since the given frame.
+func (r *Renderer) forget(frame int) {
+	for key, b := range r.blocks {
+		if b.seen < frame {
+			if b.image != nil {
+				b.image.Dispose()
+			}
+			delete(r.blocks, key)
+		}
+	}
+}
+
+// Invalidate throws away the tile layers drawn ahead of time, so that changes to their tiles
+// show up.
+func (r *Renderer) Invalidate() {
+	r.forget(r.frame + 1)
+}
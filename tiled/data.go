@@ -0,0 +1,153 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Data is the encoded contents of a tile layer. The tiles of an infinite map are split into
+// chunks instead.
+type Data struct {
+	// Encoding is csv, base64, or empty for the old XML format with a <tile> per tile.
+	Encoding string `xml:"encoding,attr"`
+	// Compression is gzip, zlib, zstd or empty, and only applies to base64.
+	Compression string `xml:"compression,attr"`
+	Text        string `xml:",chardata"`
+
+	XMLTiles []struct {
+		GID uint32 `xml:"gid,attr"`
+	} `xml:"tile"`
+
+	Chunks []*Chunk `xml:"chunk"`
+}
+
+// Chunk is a rectangle of tiles in a layer of an infinite map, where only the parts that have
+// tiles are stored. X and Y are in tiles from the map's origin and can be negative.
+type Chunk struct {
+	X      int `xml:"x,attr"`
+	Y      int `xml:"y,attr"`
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+
+	// Text and XMLTiles are encoded like the Data the chunk is in.
+	Text     string `xml:",chardata"`
+	XMLTiles []struct {
+		GID uint32 `xml:"gid,attr"`
+	} `xml:"tile"`
+
+	// Tiles are the global tile ids in the chunk, row by row, decoded by the layer.
+	Tiles []uint32 `xml:"-"`
+}
+
+// Decode returns the global tile ids, row by row.
+func (d *Data) Decode() ([]uint32, error) {
+	gids := make([]uint32, len(d.XMLTiles))
+	for i, tile := range d.XMLTiles {
+		gids[i] = tile.GID
+	}
+	return d.decode(d.Text, gids)
+}
+
+// DecodeChunk returns the global tile ids of one of the data's chunks, row by row.
+func (d *Data) DecodeChunk(c *Chunk) ([]uint32, error) {
+	gids := make([]uint32, len(c.XMLTiles))
+	for i, tile := range c.XMLTiles {
+		gids[i] = tile.GID
+	}
+	return d.decode(c.Text, gids)
+}
+
+// decode decodes text in the data's encoding, or returns the ids of the XML tiles if there
+// isn't one.
+func (d *Data) decode(text string, xmlTiles []uint32) ([]uint32, error) {
+	switch d.Encoding {
+	case "":
+		return xmlTiles, nil
+	case "csv":
+		if d.Compression != "" {
+			return nil, fmt.Errorf("csv data can't be compressed, got %q", d.Compression)
+		}
+		return decodeCSV(text)
+	case "base64":
+		return decodeBase64(text, d.Compression)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", d.Encoding)
+	}
+}
+
+func decodeCSV(text string) ([]uint32, error) {
+	var tiles []uint32
+	items := strings.Split(text, ",")
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" && i == len(items)-1 {
+			// a trailing comma
+			break
+		}
+		gid, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad tile %q: %w", item, err)
+		}
+		tiles = append(tiles, uint32(gid))
+	}
+	return tiles, nil
+}
+
+// decodeBase64 decodes little-endian 32-bit global tile ids.
+func decodeBase64(text, compression string) ([]uint32, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("bad base64 data: %w", err)
+	}
+
+	var r io.Reader = bytes.NewReader(raw)
+	switch compression {
+	case "":
+	case "zlib":
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad zlib data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad gzip data: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("bad zstd data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s data: %w", compression, err)
+	}
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("data is %d bytes, which isn't a whole number of tiles", len(b))
+	}
+
+	tiles := make([]uint32, len(b)/4)
+	for i := range tiles {
+		tiles[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return tiles, nil
+}
@@ -0,0 +1,170 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var testTiles = []uint32{1, 2, 0, 3 | FlipHorizontal, 70000}
+
+// encodeTiles encodes tiles the way Tiled writes base64 layer data.
+func encodeTiles(t *testing.T, tiles []uint32, compression string) string {
+	var raw bytes.Buffer
+	for _, gid := range tiles {
+		binary.Write(&raw, binary.LittleEndian, gid)
+	}
+	return encodeBytes(t, raw.Bytes(), compression)
+}
+
+func encodeBytes(t *testing.T, raw []byte, compression string) string {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case "":
+		buf.Write(raw)
+		return base64.StdEncoding.EncodeToString(buf.Bytes())
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	default:
+		t.Fatalf("can't compress with %q", compression)
+	}
+	if _, err := w.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDataDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		encoding    string
+		compression string
+		text        string
+		want        []uint32
+	}{
+		{"csv", "csv", "", "1,2,0,2147483651,70000", testTiles},
+		{"csv with newlines", "csv", "", "\n1,2,\n0,2147483651,\n70000\n", testTiles},
+		{"csv with trailing comma", "csv", "", "1,2,0,2147483651,70000,\n", testTiles},
+		{"base64", "base64", "", encodeTiles(t, testTiles, ""), testTiles},
+		{"base64 with whitespace", "base64", "", "\n   " + encodeTiles(t, testTiles, "") + "\n  ", testTiles},
+		{"zlib", "base64", "zlib", encodeTiles(t, testTiles, "zlib"), testTiles},
+		{"gzip", "base64", "gzip", encodeTiles(t, testTiles, "gzip"), testTiles},
+		{"zstd", "base64", "zstd", encodeTiles(t, testTiles, "zstd"), testTiles},
+		{"empty base64", "base64", "", "", []uint32{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Data{Encoding: test.encoding, Compression: test.compression, Text: test.text}
+			got, err := d.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDataDecodeXML(t *testing.T) {
+	d := &Data{}
+	for _, gid := range testTiles {
+		d.XMLTiles = append(d.XMLTiles, struct {
+			GID uint32 `xml:"gid,attr"`
+		}{gid})
+	}
+	got, err := d.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testTiles) {
+		t.Errorf("got %v, want %v", got, testTiles)
+	}
+}
+
+func TestDataDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		encoding    string
+		compression string
+		text        string
+		want        string
+	}{
+		{"unsupported encoding", "hex", "", "00", `unsupported encoding "hex"`},
+		{"compressed csv", "csv", "zlib", "1,2", "csv data can't be compressed"},
+		{"bad csv tile", "csv", "", "1,x,3", `bad tile "x"`},
+		{"negative csv tile", "csv", "", "1,-2", `bad tile "-2"`},
+		{"csv tile too big", "csv", "", "4294967296", `bad tile "4294967296"`},
+		{"empty csv tile", "csv", "", "1,,3", `bad tile ""`},
+		{"bad base64", "base64", "", "not base64!", "bad base64 data"},
+		{"unsupported compression", "base64", "lz4", encodeTiles(t, testTiles, ""), `unsupported compression "lz4"`},
+		{"partial tile", "base64", "", encodeBytes(t, []byte{1, 0, 0, 0, 2, 0}, ""), "isn't a whole number of tiles"},
+		{"partial zlib tile", "base64", "zlib", encodeBytes(t, []byte{1, 0, 0}, "zlib"), "isn't a whole number of tiles"},
+		{"bad zlib", "base64", "zlib", encodeTiles(t, testTiles, "gzip"), "bad zlib data"},
+		{"bad gzip", "base64", "gzip", encodeTiles(t, testTiles, "zlib"), "bad gzip data"},
+		{"bad zstd", "base64", "zstd", encodeTiles(t, testTiles, "gzip"), "bad zstd data"},
+		{"truncated zlib", "base64", "zlib", truncated(t, "zlib"), "bad zlib data"},
+		{"truncated gzip", "base64", "gzip", truncated(t, "gzip"), "bad gzip data"},
+		{"truncated zstd", "base64", "zstd", truncated(t, "zstd"), "bad zstd data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := &Data{Encoding: test.encoding, Compression: test.compression, Text: test.text}
+			got, err := d.Decode()
+			if err == nil {
+				t.Fatalf("got %v, want an error", got)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got error %q, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+// truncated returns compressed tile data with its end cut off.
+func truncated(t *testing.T, compression string) string {
+	tiles := make([]uint32, 256)
+	for i := range tiles {
+		tiles[i] = uint32(i * 7919)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encodeTiles(t, tiles, compression))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw[:len(raw)/2])
+}
+
+func TestDataDecodeChunk(t *testing.T) {
+	d := &Data{Encoding: "base64", Compression: "zlib", Chunks: []*Chunk{
+		{Width: 5, Height: 1, Text: encodeTiles(t, testTiles, "zlib")},
+	}}
+	got, err := d.DecodeChunk(d.Chunks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, testTiles) {
+		t.Errorf("got %v, want %v", got, testTiles)
+	}
+}
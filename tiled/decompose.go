@@ -0,0 +1,236 @@
+package tiled
+
+import (
+	"errors"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// decompose splits a simple polygon, convex or not, into convex pieces that Chipmunk can
+// collide with. It triangulates by ear clipping, then merges triangles back together for as
+// long as the result stays convex (Hertel-Mehlhorn), which gives at most four times the
+// minimum number of pieces. Polygons with edges that cross or touch each other are rejected.
+func decompose(points []cp.Vector) ([][]cp.Vector, error) {
+	points = simplify(points)
+	if len(points) < 3 {
+		return nil, errors.New("polygon needs at least 3 distinct points")
+	}
+	if selfIntersecting(points) {
+		return nil, errors.New("polygon intersects itself")
+	}
+	if signedArea(points) < 0 {
+		reversed := make([]cp.Vector, len(points))
+		for i, p := range points {
+			reversed[len(points)-1-i] = p
+		}
+		points = reversed
+	}
+
+	all := make([]int, len(points))
+	for i := range all {
+		all[i] = i
+	}
+	if convex(points, all) {
+		return [][]cp.Vector{points}, nil
+	}
+
+	pieces, err := triangulate(points)
+	if err != nil {
+		return nil, err
+	}
+	pieces = mergeConvex(points, pieces)
+
+	polys := make([][]cp.Vector, len(pieces))
+	for i, piece := range pieces {
+		for _, v := range piece {
+			polys[i] = append(polys[i], points[v])
+		}
+	}
+	return polys, nil
+}
+
+// simplify drops repeated points, a closing point equal to the first, and points in the
+// middle of a straight line.
+func simplify(points []cp.Vector) []cp.Vector {
+	var out []cp.Vector
+	for _, p := range points {
+		if len(out) > 0 && out[len(out)-1].Near(p, 1e-9) {
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) > 1 && out[0].Near(out[len(out)-1], 1e-9) {
+		out = out[:len(out)-1]
+	}
+
+	for changed := true; changed && len(out) >= 3; {
+		changed = false
+		for i := range out {
+			a, b, c := out[(i-1+len(out))%len(out)], out[i], out[(i+1)%len(out)]
+			if math.Abs(b.Sub(a).Cross(c.Sub(b))) < 1e-9 {
+				out = append(out[:i], out[i+1:]...)
+				changed = true
+				break
+			}
+		}
+	}
+	return out
+}
+
+// signedArea is positive for counter-clockwise polygons, in a y up sense.
+func signedArea(points []cp.Vector) float64 {
+	var area float64
+	for i, p := range points {
+		area += p.Cross(points[(i+1)%len(points)])
+	}
+	return area / 2
+}
+
+// selfIntersecting reports whether any two edges of the polygon that aren't next to each other
+// cross or touch. Ear clipping happily cuts up some polygons like these, a bowtie for one, into
+// pieces that overlap.
+func selfIntersecting(points []cp.Vector) bool {
+	n := len(points)
+	for i := 0; i < n; i++ {
+		a, b := points[i], points[(i+1)%n]
+		// the edges after i+1 up to the one before i
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if segmentsIntersect(a, b, points[j], points[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect reports whether segment ab crosses or touches segment cd.
+func segmentsIntersect(a, b, c, d cp.Vector) bool {
+	d1 := orientation(c, d, a)
+	d2 := orientation(c, d, b)
+	d3 := orientation(a, b, c)
+	d4 := orientation(a, b, d)
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return (d1 == 0 && onSegment(a, c, d)) || (d2 == 0 && onSegment(b, c, d)) ||
+		(d3 == 0 && onSegment(c, a, b)) || (d4 == 0 && onSegment(d, a, b))
+}
+
+// orientation is the side of ab that p is on, as -1, 0 or 1.
+func orientation(a, b, p cp.Vector) float64 {
+	cross := b.Sub(a).Cross(p.Sub(a))
+	switch {
+	case cross > 1e-9:
+		return 1
+	case cross < -1e-9:
+		return -1
+	}
+	return 0
+}
+
+// onSegment reports whether p, which is on the line through ab, is between a and b.
+func onSegment(p, a, b cp.Vector) bool {
+	return p.X >= math.Min(a.X, b.X)-1e-9 && p.X <= math.Max(a.X, b.X)+1e-9 &&
+		p.Y >= math.Min(a.Y, b.Y)-1e-9 && p.Y <= math.Max(a.Y, b.Y)+1e-9
+}
+
+// convex reports whether the counter-clockwise polygon made of the given points is convex.
+func convex(points []cp.Vector, poly []int) bool {
+	for i := range poly {
+		a := points[poly[(i-1+len(poly))%len(poly)]]
+		b := points[poly[i]]
+		c := points[poly[(i+1)%len(poly)]]
+		if b.Sub(a).Cross(c.Sub(b)) < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func triangulate(points []cp.Vector) ([][]int, error) {
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	var triangles [][]int
+	for len(remaining) > 3 {
+		found := false
+		for i := range remaining {
+			ia := remaining[(i-1+len(remaining))%len(remaining)]
+			ib := remaining[i]
+			ic := remaining[(i+1)%len(remaining)]
+			a, b, c := points[ia], points[ib], points[ic]
+			if b.Sub(a).Cross(c.Sub(b)) <= 0 {
+				// reflex
+				continue
+			}
+			ear := true
+			for _, j := range remaining {
+				if j != ia && j != ib && j != ic && inTriangle(points[j], a, b, c) {
+					ear = false
+					break
+				}
+			}
+			if ear {
+				triangles = append(triangles, []int{ia, ib, ic})
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("polygon intersects itself")
+		}
+	}
+	return append(triangles, remaining), nil
+}
+
+// inTriangle reports whether p is inside or on the edge of the counter-clockwise triangle.
+func inTriangle(p, a, b, c cp.Vector) bool {
+	return b.Sub(a).Cross(p.Sub(a)) >= 0 && c.Sub(b).Cross(p.Sub(b)) >= 0 && a.Sub(c).Cross(p.Sub(c)) >= 0
+}
+
+func mergeConvex(points []cp.Vector, pieces [][]int) [][]int {
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(pieces) && !merged; i++ {
+			for j := i + 1; j < len(pieces) && !merged; j++ {
+				joined, ok := join(pieces[i], pieces[j])
+				if !ok || !convex(points, joined) {
+					continue
+				}
+				pieces[i] = joined
+				pieces = append(pieces[:j], pieces[j+1:]...)
+				merged = true
+			}
+		}
+	}
+	return pieces
+}
+
+// join combines two counter-clockwise polygons that share an edge into one.
+func join(a, b []int) ([]int, bool) {
+	for i := range a {
+		u, v := a[i], a[(i+1)%len(a)]
+		for j := range b {
+			if b[j] != v || b[(j+1)%len(b)] != u {
+				continue
+			}
+			// walk a from v round to u, then b from u round to v without repeating either
+			var out []int
+			for k := 1; k <= len(a); k++ {
+				out = append(out, a[(i+k)%len(a)])
+			}
+			for k := 2; k < len(b); k++ {
+				out = append(out, b[(j+k)%len(b)])
+			}
+			return out, true
+		}
+	}
+	return nil, false
+}
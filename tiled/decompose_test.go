@@ -0,0 +1,78 @@
+package tiled
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+func TestDecompose(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []cp.Vector
+		// pieces is how many convex pieces to expect, and area their total area
+		pieces int
+		area   float64
+	}{
+		{"square", []cp.Vector{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, 1, 100},
+		{"clockwise square", []cp.Vector{{0, 0}, {0, 10}, {10, 10}, {10, 0}}, 1, 100},
+		{"concave L", []cp.Vector{{0, 0}, {20, 0}, {20, 10}, {10, 10}, {10, 20}, {0, 20}}, 2, 300},
+		{"concave arrow", []cp.Vector{{0, 0}, {10, 5}, {20, 0}, {10, 20}}, 2, 150},
+		{"concave U", []cp.Vector{{0, 0}, {30, 0}, {30, 20}, {20, 20}, {20, 10}, {10, 10}, {10, 20}, {0, 20}}, 3, 500},
+		{"collinear points", []cp.Vector{{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}, {0, 10}}, 1, 100},
+		{"duplicate points", []cp.Vector{{0, 0}, {0, 0}, {10, 0}, {10, 10}, {10, 10}, {0, 10}, {0, 0}}, 1, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			polys, err := decompose(test.points)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(polys) != test.pieces {
+				t.Errorf("got %d pieces, want %d: %v", len(polys), test.pieces, polys)
+			}
+			var area float64
+			for _, poly := range polys {
+				if signedArea(poly) <= 0 {
+					t.Errorf("piece %v isn't counter-clockwise", poly)
+				}
+				all := make([]int, len(poly))
+				for i := range all {
+					all[i] = i
+				}
+				if !convex(poly, all) {
+					t.Errorf("piece %v isn't convex", poly)
+				}
+				area += signedArea(poly)
+			}
+			if math.Abs(area-test.area) > 1e-9 {
+				t.Errorf("pieces cover %v, want %v", area, test.area)
+			}
+		})
+	}
+}
+
+func TestDecomposeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []cp.Vector
+	}{
+		{"too few points", []cp.Vector{{0, 0}, {10, 0}}},
+		{"all duplicates", []cp.Vector{{5, 5}, {5, 5}, {5, 5}}},
+		{"all collinear", []cp.Vector{{0, 0}, {5, 0}, {10, 0}}},
+		{"bowtie", []cp.Vector{{0, 0}, {10, 10}, {10, 0}, {0, 10}}},
+		{"crossed concave", []cp.Vector{{0, 0}, {20, 0}, {20, 20}, {10, -10}, {0, 20}}},
+		{"touching itself", []cp.Vector{{0, 0}, {20, 0}, {20, 20}, {10, 0}, {0, 20}}},
+		{"repeated vertex", []cp.Vector{{0, 0}, {10, 0}, {10, 10}, {20, 10}, {20, 20}, {10, 20}, {10, 10}, {0, 10}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if polys, err := decompose(test.points); err == nil {
+				t.Errorf("got %v, want an error", polys)
+			}
+		})
+	}
+}
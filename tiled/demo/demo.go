@@ -0,0 +1,220 @@
+//go:build !headless
+
+package main
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten"
+	"github.com/jakecoffman/cpebiten/tiled"
+	"golang.org/x/image/math/f64"
+	"image/color"
+	"log"
+	"math"
+)
+
+type Game struct {
+	Game     *cpebiten.Game
+	map1     *tiled.Map
+	renderer *tiled.Renderer
+	streamer *tiled.Streamer
+
+	camera Camera
+
+	drawPhysics bool
+}
+
+type Camera struct {
+	ViewPort   f64.Vec2
+	Position   f64.Vec2
+	ZoomFactor int
+	Rotation   int
+}
+
+func (c *Camera) String() string {
+	return fmt.Sprintf(
+		"T: %.1f, R: %d, S: %d",
+		c.Position, c.Rotation, c.ZoomFactor,
+	)
+}
+
+func (c *Camera) viewportCenter() f64.Vec2 {
+	return f64.Vec2{
+		c.ViewPort[0] * 0.5,
+		c.ViewPort[1] * 0.5,
+	}
+}
+
+func (c *Camera) worldMatrix() ebiten.GeoM {
+	m := ebiten.GeoM{}
+	m.Translate(-c.Position[0], -c.Position[1])
+	// We want to scale and rotate around center of image / screen
+	m.Translate(-c.viewportCenter()[0], -c.viewportCenter()[1])
+	m.Scale(
+		math.Pow(1.01, float64(c.ZoomFactor)),
+		math.Pow(1.01, float64(c.ZoomFactor)),
+	)
+	m.Rotate(float64(c.Rotation) * 2 * math.Pi / 360)
+	m.Translate(c.viewportCenter()[0], c.viewportCenter()[1])
+	return m
+}
+
+func (c *Camera) Render(renderer *tiled.Renderer, screen *ebiten.Image) {
+	renderer.Draw(screen, c.worldMatrix())
+}
+
+func (c *Camera) ScreenToWorld(posX, posY int) (float64, float64) {
+	inverseMatrix := c.worldMatrix()
+	if inverseMatrix.IsInvertible() {
+		inverseMatrix.Invert()
+		return inverseMatrix.Apply(float64(posX), float64(posY))
+	} else {
+		// When scaling it can happend that matrix is not invertable
+		return math.NaN(), math.NaN()
+	}
+}
+
+// Visible returns the part of the world that is on screen.
+func (c *Camera) Visible() cp.BB {
+	bb := cp.BB{L: math.Inf(1), B: math.Inf(1), R: math.Inf(-1), T: math.Inf(-1)}
+	for _, corner := range [][2]int{{0, 0}, {screenWidth, 0}, {0, screenHeight}, {screenWidth, screenHeight}} {
+		x, y := c.ScreenToWorld(corner[0], corner[1])
+		bb = bb.Expand(cp.Vector{X: x, Y: y})
+	}
+	return bb
+}
+
+func (c *Camera) Reset() {
+	c.Position[0] = 0
+	c.Position[1] = 0
+	c.Rotation = 0
+	c.ZoomFactor = 0
+}
+
+func NewGame() (*Game, error) {
+	map1, err := tiled.LoadFile("tiled/map1.tmx")
+	if err != nil {
+		return nil, err
+	}
+
+	renderer, err := tiled.NewRenderer(map1)
+	if err != nil {
+		return nil, err
+	}
+
+	space := cp.NewSpace()
+
+	if _, err := tiled.AddObjects(space, map1); err != nil {
+		return nil, err
+	}
+
+	worldWidth, worldHeight := map1.Width*map1.TileHeight, map1.Height*map1.TileWidth
+
+	return &Game{
+		Game:     cpebiten.NewGame(space, 60),
+		map1:     map1,
+		renderer: renderer,
+		streamer: tiled.NewStreamer(space, map1),
+		camera: Camera{
+			ViewPort:   f64.Vec2{float64(worldWidth), float64(worldHeight)},
+			Position:   f64.Vec2{-100, -70},
+			ZoomFactor: 100,
+			Rotation:   0,
+		},
+	}, nil
+}
+
+func (g *Game) Update() error {
+	if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
+		g.camera.Position[0] -= 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
+		g.camera.Position[0] += 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp) {
+		g.camera.Position[1] -= 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyDown) {
+		g.camera.Position[1] += 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyQ) {
+		g.camera.ZoomFactor -= 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyE) {
+		g.camera.ZoomFactor += 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyR) {
+		g.camera.Rotation += 1
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeySpace) {
+		g.camera.Reset()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.drawPhysics = !g.drawPhysics
+	}
+
+	g.renderer.Update(1.0 / 60)
+
+	// only keep the colliders of the tiles near the camera in the space
+	if err := g.streamer.Update(g.camera.Visible()); err != nil {
+		return err
+	}
+
+	if err := g.Game.Update(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.Black)
+
+	g.camera.Render(g.renderer, screen)
+
+	if g.drawPhysics {
+		// draw straight to the screen through the camera so the shapes stay sharp when zoomed
+		op := cpebiten.NewDrawOptionsView(screen, g.camera.worldMatrix())
+		cpebiten.DrawSpace(g.Game.Space, op)
+		op.Flush()
+	}
+
+	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %0.2f FPS: %0.2f", ebiten.CurrentTPS(), ebiten.CurrentFPS()))
+	worldX, worldY := g.camera.ScreenToWorld(ebiten.CursorPosition())
+	ebitenutil.DebugPrint(
+		screen,
+		fmt.Sprintf("TPS: %0.2f\nMove (WASD/Arrows)\nZoom (QE)\nRotate (R)\nReset (Space)\nChunks: %d", ebiten.CurrentTPS(), g.streamer.Loaded()),
+	)
+	ebitenutil.DebugPrintAt(
+		screen,
+		fmt.Sprintf("%s\nCursor World Pos: %.2f,%.2f",
+			g.camera.String(),
+			worldX, worldY),
+		0, screenHeight-32,
+	)
+}
+
+func (g *Game) Layout(int, int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+const screenWidth, screenHeight = 800, 600
+
+func main() {
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Tumble")
+	game, err := NewGame()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ebiten.RunGame(game); err != nil {
+		log.Fatal(err)
+	}
+}
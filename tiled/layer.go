@@ -0,0 +1,197 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+)
+
+// LayerKind is the type of a layer, from the name of its element.
+type LayerKind string
+
+const (
+	TileLayer   LayerKind = "layer"
+	ObjectGroup LayerKind = "objectgroup"
+	ImageLayer  LayerKind = "imagelayer"
+	GroupLayer  LayerKind = "group"
+)
+
+// Layer is any kind of layer. Which of the fields below the common ones are used depends on
+// the Kind.
+type Layer struct {
+	Kind    LayerKind `xml:"-"`
+	ID      int       `xml:"id,attr"`
+	Name    string    `xml:"name,attr"`
+	Visible bool      `xml:"visible,attr"`
+	Opacity float64   `xml:"opacity,attr"`
+	OffsetX float64   `xml:"offsetx,attr"`
+	OffsetY float64   `xml:"offsety,attr"`
+	// ParallaxX and ParallaxY are how fast the layer scrolls relative to the camera. 1 moves
+	// with the map, less than 1 is further away and 0 stays fixed on screen.
+	ParallaxX float64 `xml:"parallaxx,attr"`
+	ParallaxY float64 `xml:"parallaxy,attr"`
+
+	Properties Properties `xml:"properties>property"`
+
+	// Width and Height are the size of a tile layer in tiles. In an infinite map they are
+	// only how big the layer was when it was saved; see Bounds.
+	Width  int   `xml:"width,attr"`
+	Height int   `xml:"height,attr"`
+	Data   *Data `xml:"data"`
+	// Tiles are the global tile ids of a tile layer, row by row, decoded from Data. They
+	// include the flip flags, see SplitGID. Tiles is nil in an infinite map.
+	Tiles []uint32 `xml:"-"`
+	// Chunks are the parts of a tile layer that have tiles, decoded from Data. A layer of a
+	// map that isn't infinite is one chunk of all of its Tiles.
+	Chunks []*Chunk `xml:"-"`
+
+	// Objects are the contents of an object group.
+	Objects   []*Object `xml:"object"`
+	DrawOrder string    `xml:"draworder,attr"`
+	Color     string    `xml:"color,attr"`
+
+	// Image is the picture shown by an image layer.
+	Image *Image `xml:"image"`
+
+	// Layers are the children of a group, bottom first.
+	Layers layerList `xml:",any"`
+
+	// chunkWidth and chunkHeight are the size of every chunk of an infinite layer, if they
+	// are all the same size and line up, in which case chunks finds a chunk by its position.
+	chunkWidth, chunkHeight int
+	chunks                  map[image.Point]*Chunk
+}
+
+func (l *Layer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type layer Layer
+	decoded := layer{Kind: LayerKind(start.Name.Local), Visible: true, Opacity: 1, ParallaxX: 1, ParallaxY: 1}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	*l = Layer(decoded)
+	return nil
+}
+
+// TileAt returns the global tile id at a position in a tile layer, or 0 outside of it.
+func (l *Layer) TileAt(x, y int) uint32 {
+	if l.Tiles != nil {
+		if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+			return 0
+		}
+		return l.Tiles[y*l.Width+x]
+	}
+	c := l.chunkAt(x, y)
+	if c == nil {
+		return 0
+	}
+	return c.Tiles[(y-c.Y)*c.Width+x-c.X]
+}
+
+// Bounds returns the rectangle of tiles a tile layer covers.
+func (l *Layer) Bounds() image.Rectangle {
+	var bounds image.Rectangle
+	for _, c := range l.Chunks {
+		bounds = bounds.Union(c.Bounds())
+	}
+	return bounds
+}
+
+// Bounds returns the rectangle of tiles the chunk covers.
+func (c *Chunk) Bounds() image.Rectangle {
+	return image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+}
+
+func (l *Layer) chunkAt(x, y int) *Chunk {
+	if l.chunks != nil {
+		return l.chunks[image.Pt(floorDiv(x, l.chunkWidth)*l.chunkWidth, floorDiv(y, l.chunkHeight)*l.chunkHeight)]
+	}
+	p := image.Pt(x, y)
+	for _, c := range l.Chunks {
+		if p.In(c.Bounds()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// floorDiv divides rounding down, rather than towards zero, for chunks left of or above the
+// origin.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// layerList collects the layers of a map or group in document order, whatever their kind.
+type layerList []*Layer
+
+func (list *layerList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch LayerKind(start.Name.Local) {
+	case TileLayer, ObjectGroup, ImageLayer, GroupLayer:
+		layer := &Layer{}
+		if err := d.DecodeElement(layer, &start); err != nil {
+			return err
+		}
+		*list = append(*list, layer)
+		return nil
+	default:
+		return d.Skip()
+	}
+}
+
+// decode fills in Tiles and Chunks from Data for tile layers.
+func (l *Layer) decode() error {
+	if l.Kind != TileLayer {
+		return nil
+	}
+	if l.Data == nil {
+		return fmt.Errorf("layer %q has no data", l.Name)
+	}
+
+	if len(l.Data.Chunks) > 0 {
+		return l.decodeChunks()
+	}
+
+	tiles, err := l.Data.Decode()
+	if err != nil {
+		return fmt.Errorf("layer %q: %w", l.Name, err)
+	}
+
+	if len(tiles) != l.Width*l.Height {
+		return fmt.Errorf("layer %q: has %d tiles, want %dx%d", l.Name, len(tiles), l.Width, l.Height)
+	}
+	l.Tiles = tiles
+	l.Chunks = []*Chunk{{Width: l.Width, Height: l.Height, Tiles: tiles}}
+	return nil
+}
+
+func (l *Layer) decodeChunks() error {
+	l.Chunks = l.Data.Chunks
+	aligned := true
+	for _, c := range l.Chunks {
+		tiles, err := l.Data.DecodeChunk(c)
+		if err != nil {
+			return fmt.Errorf("layer %q: chunk at %d,%d: %w", l.Name, c.X, c.Y, err)
+		}
+		if len(tiles) != c.Width*c.Height {
+			return fmt.Errorf("layer %q: chunk at %d,%d: has %d tiles, want %dx%d", l.Name, c.X, c.Y, len(tiles), c.Width, c.Height)
+		}
+		c.Tiles = tiles
+
+		first := l.Chunks[0]
+		if c.Width != first.Width || c.Height != first.Height || c.Width <= 0 || c.Height <= 0 || c.X%c.Width != 0 || c.Y%c.Height != 0 {
+			aligned = false
+		}
+	}
+
+	if aligned {
+		l.chunkWidth, l.chunkHeight = l.Chunks[0].Width, l.Chunks[0].Height
+		l.chunks = map[image.Point]*Chunk{}
+		for _, c := range l.Chunks {
+			l.chunks[image.Pt(c.X, c.Y)] = c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Object is a shape placed in an object group. Unless it is an ellipse, point, polygon or
+// polyline, it is a rectangle, or a tile if GID is set.
+type Object struct {
+	ID       int     `xml:"id,attr"`
+	Name     string  `xml:"name,attr"`
+	Type     string  `xml:"type,attr"`
+	X        float64 `xml:"x,attr"`
+	Y        float64 `xml:"y,attr"`
+	Width    float64 `xml:"width,attr"`
+	Height   float64 `xml:"height,attr"`
+	Rotation float64 `xml:"rotation,attr"`
+	GID      uint32  `xml:"gid,attr"`
+	Visible  bool    `xml:"visible,attr"`
+
+	Properties Properties `xml:"properties>property"`
+
+	Ellipse  *struct{} `xml:"ellipse"`
+	Point    *struct{} `xml:"point"`
+	Polygon  *Poly     `xml:"polygon"`
+	Polyline *Poly     `xml:"polyline"`
+}
+
+func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type object Object
+	decoded := object{Visible: true}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	*o = Object(decoded)
+	return nil
+}
+
+// Poly is the outline of a polygon or polyline object, relative to the object's position.
+type Poly struct {
+	Points Points `xml:"points,attr"`
+}
+
+// Points is a list of points written as "x1,y1 x2,y2 ...".
+type Points []cp.Vector
+
+func (p *Points) UnmarshalXMLAttr(attr xml.Attr) error {
+	*p = (*p)[:0]
+	for _, pair := range strings.Fields(attr.Value) {
+		xy := strings.Split(pair, ",")
+		if len(xy) != 2 {
+			return fmt.Errorf("bad point %q", pair)
+		}
+		x, err := strconv.ParseFloat(xy[0], 64)
+		if err != nil {
+			return fmt.Errorf("bad point %q: %w", pair, err)
+		}
+		y, err := strconv.ParseFloat(xy[1], 64)
+		if err != nil {
+			return fmt.Errorf("bad point %q: %w", pair, err)
+		}
+		*p = append(*p, cp.Vector{X: x, Y: y})
+	}
+	return nil
+}
@@ -0,0 +1,559 @@
+package tiled
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// ellipseSegments is how many sides the polygon standing in for a non-circular ellipse has.
+const ellipseSegments = 16
+
+// Spawn is a point object, such as where the player or an enemy starts.
+type Spawn struct {
+	Object *Object
+	// Position includes the offsets of the layers the object is in.
+	Position cp.Vector
+}
+
+// AddObjects adds collision shapes to the space for the objects in every object group of the
+// map, including hidden ones, since collision is often kept on a layer that isn't drawn. Point
+// objects don't collide and are returned as spawns instead. Each shape's UserData is its
+// object, as is the body's for objects that get a body of their own.
+//
+// How an object behaves is set with custom properties, looked up on the object, then on the
+// tile of a tile object, then on the object groups it is in from the innermost out:
+//
+//	body        static (the default), kinematic or dynamic
+//	mass        the mass of a dynamic body, 1 by default
+//	friction    0.7 by default
+//	elasticity  0 by default
+//	sensor      true to report collisions without responding to them
+//	collisionType
+//	            the type collision handlers are registered for
+//	filterGroup shapes in the same group, other than 0, don't collide
+//	filterCategories, filterMask
+//	            bit masks of the categories a shape is in and collides with, all by default
+//
+// Static objects share the space's static body. Others get a body positioned at their center
+// of gravity. A tile object whose tile has collision shapes drawn on it collides with those,
+// stretched and turned along with the object, instead of its rectangle.
+func AddObjects(space *cp.Space, m *Map) ([]Spawn, error) {
+	into := &loaded{space: space}
+	var spawns []Spawn
+	err := walkLayers(m.Layers, cp.Vector{}, nil, func(layer *Layer, offset cp.Vector, props Properties) error {
+		for _, object := range layer.Objects {
+			if object.Point != nil {
+				spawns = append(spawns, Spawn{object, offset.Add(cp.Vector{X: object.X, Y: object.Y})})
+				continue
+			}
+			if err := addObject(into, m, object, offset, props); err != nil {
+				return fmt.Errorf("object %d: %w", object.ID, err)
+			}
+		}
+		return nil
+	})
+	return spawns, err
+}
+
+// AddTileColliders adds the collision shapes drawn on tiles in the tileset editor for every
+// tile placed in a tile layer, hidden or not. The shapes are static unless the tile or layer
+// says otherwise, in which case each placed tile gets a body of its own. Properties are
+// looked up on the collider object, then on the tile, then on the layers as for AddObjects.
+// Each shape's UserData is the collider object in the tileset, or a mirrored copy of it where
+// the tile is flipped.
+func AddTileColliders(space *cp.Space, m *Map) error {
+	into := &loaded{space: space}
+	return walkLayers(m.Layers, cp.Vector{}, nil, func(layer *Layer, offset cp.Vector, props Properties) error {
+		if layer.Kind != TileLayer {
+			return nil
+		}
+		for _, chunk := range layer.Chunks {
+			if err := addTileColliders(into, m, chunk, offset, props); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addTileColliders adds the colliders of the tiles in a chunk of a layer.
+func addTileColliders(into *loaded, m *Map, chunk *Chunk, offset cp.Vector, props Properties) error {
+	for i, gid := range chunk.Tiles {
+		ts, id := m.TileSetFor(gid)
+		if ts == nil {
+			continue
+		}
+		tile := ts.Tile(id)
+		if tile == nil || tile.ObjectGroup == nil {
+			continue
+		}
+		x, y := chunk.X+i%chunk.Width, chunk.Y+i/chunk.Width
+		_, flags := SplitGID(gid)
+		w, h := ts.TileSize(id)
+		parts := colliders(tile)
+		for i, part := range parts {
+			parts[i] = part.mirror(flags, float64(w), float64(h))
+		}
+		if flags&FlipDiagonal != 0 {
+			h = w
+		}
+		// like when drawn, a tile taller than the grid sticks up from its cell
+		origin := offset.Add(cp.Vector{X: float64(x * m.TileWidth), Y: float64((y+1)*m.TileHeight - h)})
+		if err := addParts(into, nil, parts, origin, inherit(tile.Properties, props)); err != nil {
+			return fmt.Errorf("tile at %d,%d: %w", x, y, err)
+		}
+	}
+	return nil
+}
+
+// addObject adds the shapes of an object in an object group. A tile object with colliders
+// drawn on its tile collides with those instead of its rectangle.
+func addObject(into *loaded, m *Map, object *Object, offset cp.Vector, props Properties) error {
+	parts := []*Object{object}
+	if ts, id := m.TileSetFor(object.GID); ts != nil {
+		if tile := ts.Tile(id); tile != nil {
+			props = inherit(tile.Properties, props)
+			if tile.ObjectGroup != nil {
+				w, h := ts.TileSize(id)
+				parts = parts[:0]
+				_, flags := SplitGID(object.GID)
+				for _, collider := range colliders(tile) {
+					collider = collider.mirror(flags, float64(w), float64(h))
+					parts = append(parts, object.place(collider, w, h))
+				}
+			}
+		}
+	}
+	return addParts(into, object, parts, offset, inherit(object.Properties, props))
+}
+
+// addParts adds the shapes of parts, moved by offset, on one body. The body and its material
+// come from props, though each part can change the material with properties of its own. The
+// UserData of the body and shapes is owner, or each part's own object if owner is nil.
+func addParts(into *loaded, owner *Object, parts []*Object, offset cp.Vector, props Properties) error {
+	if len(parts) == 0 {
+		return nil
+	}
+	settings, err := physicsFrom(props)
+	if err != nil {
+		return err
+	}
+
+	body := into.space.StaticBody
+	if settings.bodyType != cp.BODY_STATIC {
+		center, moment := massProperties(parts, settings.mass)
+		center = offset.Add(center)
+		if settings.bodyType == cp.BODY_DYNAMIC {
+			body = cp.NewBody(settings.mass, moment)
+		} else {
+			body = cp.NewKinematicBody()
+		}
+		body.SetPosition(center)
+		if owner != nil {
+			body.UserData = owner
+		}
+		offset = offset.Sub(center)
+		into.addBody(body)
+	}
+
+	for _, part := range parts {
+		material := settings
+		if part != owner && len(part.Properties) > 0 {
+			if material, err = physicsFrom(inherit(part.Properties, props)); err != nil {
+				return fmt.Errorf("collider %d: %w", part.ID, err)
+			}
+		}
+		shapes, err := part.Shapes(body, offset)
+		if err != nil {
+			return fmt.Errorf("collider %d: %w", part.ID, err)
+		}
+		for _, shape := range shapes {
+			material.apply(shape)
+			if owner != nil {
+				shape.UserData = owner
+			} else {
+				shape.UserData = part
+			}
+			into.addShape(shape)
+		}
+	}
+	return nil
+}
+
+// loaded is what has been added to a space, so that it can be taken out again.
+type loaded struct {
+	space  *cp.Space
+	shapes []*cp.Shape
+	bodies []*cp.Body
+}
+
+func (l *loaded) addShape(shape *cp.Shape) {
+	l.space.AddShape(shape)
+	l.shapes = append(l.shapes, shape)
+}
+
+func (l *loaded) addBody(body *cp.Body) {
+	l.space.AddBody(body)
+	l.bodies = append(l.bodies, body)
+}
+
+// remove takes everything that was added back out of the space.
+func (l *loaded) remove() {
+	for _, shape := range l.shapes {
+		l.space.RemoveShape(shape)
+	}
+	for _, body := range l.bodies {
+		l.space.RemoveBody(body)
+	}
+	l.shapes, l.bodies = nil, nil
+}
+
+// colliders returns the objects drawn on a tile that have a shape.
+func colliders(tile *Tile) []*Object {
+	var objects []*Object
+	for _, object := range tile.ObjectGroup.Objects {
+		if object.Point == nil {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// inherit returns own followed by inherited, so that Get finds own properties first.
+func inherit(own, inherited Properties) Properties {
+	if len(own) == 0 {
+		return inherited
+	}
+	return append(append(Properties{}, own...), inherited...)
+}
+
+// place returns a collider drawn on the tile of a tile object as an object of the map, moved,
+// stretched and turned along with the tile object. Stretching a rotated collider by different
+// amounts along each axis only keeps its size, not its angles.
+func (o *Object) place(collider *Object, tileWidth, tileHeight int) *Object {
+	sx, sy := 1.0, 1.0
+	if o.Width > 0 && tileWidth > 0 {
+		sx = o.Width / float64(tileWidth)
+	}
+	if o.Height > 0 && tileHeight > 0 {
+		sy = o.Height / float64(tileHeight)
+	}
+	scale := func(points Points) Points {
+		scaled := make(Points, len(points))
+		for i, p := range points {
+			scaled[i] = cp.Vector{X: p.X * sx, Y: p.Y * sy}
+		}
+		return scaled
+	}
+
+	placed := *collider
+	// tile objects are positioned by their bottom left corner
+	transform := cp.NewTransformRigid(cp.Vector{X: o.X, Y: o.Y}, o.Rotation*math.Pi/180)
+	position := transform.Point(cp.Vector{X: collider.X * sx, Y: collider.Y*sy - float64(tileHeight)*sy})
+	placed.X, placed.Y = position.X, position.Y
+	placed.Width, placed.Height = collider.Width*sx, collider.Height*sy
+	placed.Rotation = o.Rotation + collider.Rotation
+	if collider.Polygon != nil {
+		placed.Polygon = &Poly{Points: scale(collider.Polygon.Points)}
+	}
+	if collider.Polyline != nil {
+		placed.Polyline = &Poly{Points: scale(collider.Polyline.Points)}
+	}
+	return &placed
+}
+
+// physics is what an object's properties say about how it collides.
+type physics struct {
+	bodyType   int
+	mass       float64
+	friction   float64
+	elasticity float64
+	sensor     bool
+	collision  cp.CollisionType
+	filter     cp.ShapeFilter
+}
+
+func physicsFrom(props Properties) (physics, error) {
+	p := physics{bodyType: cp.BODY_STATIC, filter: cp.SHAPE_FILTER_ALL}
+
+	switch body := props.String("body", "static"); body {
+	case "static":
+	case "kinematic":
+		p.bodyType = cp.BODY_KINEMATIC
+	case "dynamic":
+		p.bodyType = cp.BODY_DYNAMIC
+	default:
+		return p, fmt.Errorf("property %q: unknown body type %q", "body", body)
+	}
+
+	var err error
+	if p.mass, err = props.Float("mass", 1); err != nil {
+		return p, err
+	}
+	if p.mass <= 0 && p.bodyType == cp.BODY_DYNAMIC {
+		return p, fmt.Errorf("property %q: a dynamic body needs a positive mass", "mass")
+	}
+	if p.friction, err = props.Float("friction", 0.7); err != nil {
+		return p, err
+	}
+	if p.elasticity, err = props.Float("elasticity", 0); err != nil {
+		return p, err
+	}
+	if p.sensor, err = props.Bool("sensor", false); err != nil {
+		return p, err
+	}
+	collision, err := props.Uint("collisionType", 0)
+	if err != nil {
+		return p, err
+	}
+	p.collision = cp.CollisionType(collision)
+	if p.filter.Group, err = props.Uint("filterGroup", p.filter.Group); err != nil {
+		return p, err
+	}
+	if p.filter.Categories, err = props.Uint("filterCategories", p.filter.Categories); err != nil {
+		return p, err
+	}
+	if p.filter.Mask, err = props.Uint("filterMask", p.filter.Mask); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func (p physics) apply(shape *cp.Shape) {
+	shape.SetFriction(p.friction)
+	shape.SetElasticity(p.elasticity)
+	shape.SetSensor(p.sensor)
+	shape.SetCollisionType(p.collision)
+	shape.SetFilter(p.filter)
+}
+
+// mirror returns a collider drawn on a w by h tile as it is on a tile placed with the given
+// flip flags. Mirroring can leave a rotated rectangle or ellipse at an angle an object can't
+// describe, so anything but a circle becomes a polygon with the same outline.
+func (o *Object) mirror(flags uint32, w, h float64) *Object {
+	if flags&(FlipHorizontal|FlipVertical|FlipDiagonal) == 0 {
+		return o
+	}
+	if flags&FlipDiagonal != 0 {
+		// the other flips happen across the tile once it is on its side
+		w, h = h, w
+	}
+	mirror := func(p cp.Vector) cp.Vector {
+		if flags&FlipDiagonal != 0 {
+			p.X, p.Y = p.Y, p.X
+		}
+		if flags&FlipHorizontal != 0 {
+			p.X = w - p.X
+		}
+		if flags&FlipVertical != 0 {
+			p.Y = h - p.Y
+		}
+		return p
+	}
+
+	transform := cp.NewTransformRigid(cp.Vector{X: o.X, Y: o.Y}, o.Rotation*math.Pi/180)
+	mirrored := *o
+	mirrored.X, mirrored.Y, mirrored.Rotation = 0, 0, 0
+	outline := func(points []cp.Vector) *Poly {
+		mirrored := make(Points, len(points))
+		for i, p := range points {
+			mirrored[i] = mirror(transform.Point(p))
+		}
+		return &Poly{Points: mirrored}
+	}
+
+	switch {
+	case o.Point != nil:
+		p := mirror(transform.Point(cp.Vector{}))
+		mirrored.X, mirrored.Y = p.X, p.Y
+	case o.Polygon != nil:
+		mirrored.Polygon = outline(o.Polygon.Points)
+	case o.Polyline != nil:
+		mirrored.Polyline = outline(o.Polyline.Points)
+	case o.Width <= 0 || o.Height <= 0:
+		return o
+	case o.Ellipse != nil && math.Abs(o.Width-o.Height) < 1e-6:
+		r := o.Width / 2
+		center := mirror(transform.Point(cp.Vector{X: r, Y: r}))
+		mirrored.X, mirrored.Y = center.X-r, center.Y-r
+	case o.Ellipse != nil:
+		mirrored.Ellipse = nil
+		mirrored.Polygon = outline(ellipse(o.Width, o.Height))
+	case o.GID != 0:
+		mirrored.GID = 0
+		mirrored.Polygon = outline(box(0, -o.Height, o.Width, 0))
+	default:
+		mirrored.Polygon = outline(box(0, 0, o.Width, o.Height))
+	}
+	return &mirrored
+}
+
+// walkLayers calls f with every layer in draw order, the sum of its offset and those of the
+// groups it is in, and its properties followed by theirs. Errors from f are prefixed with the
+// name of the layer.
+func walkLayers(layers []*Layer, offset cp.Vector, props Properties, f func(layer *Layer, offset cp.Vector, props Properties) error) error {
+	for _, layer := range layers {
+		offset := offset.Add(cp.Vector{X: layer.OffsetX, Y: layer.OffsetY})
+		props := inherit(layer.Properties, props)
+		if err := f(layer, offset, props); err != nil {
+			return fmt.Errorf("layer %q: %w", layer.Name, err)
+		}
+		if err := walkLayers(layer.Layers, offset, props, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shapes creates collision shapes for the object on body, without adding them to a space.
+// The shapes are placed at the object's map position plus offset, so for a body that isn't
+// static, subtract its position from offset. Rectangles and tile objects become boxes that
+// keep their rotation, ellipses become circles or polygons, polygons are split into convex
+// pieces, and polylines become a chain of segments. Points have no shape.
+func (o *Object) Shapes(body *cp.Body, offset cp.Vector) ([]*cp.Shape, error) {
+	// Tiled rotates objects clockwise in degrees about their position, which is the top left
+	// corner, or the bottom left for tiles
+	transform := cp.NewTransformRigid(offset.Add(cp.Vector{X: o.X, Y: o.Y}), o.Rotation*math.Pi/180)
+
+	poly := func(points []cp.Vector) *cp.Shape {
+		return cp.NewPolyShape(body, len(points), points, transform, 0)
+	}
+
+	switch {
+	case o.Point != nil:
+		return nil, nil
+
+	case o.Polygon != nil:
+		pieces, err := decompose(o.Polygon.Points)
+		if err != nil {
+			return nil, err
+		}
+		var shapes []*cp.Shape
+		for _, piece := range pieces {
+			shapes = append(shapes, poly(piece))
+		}
+		return shapes, nil
+
+	case o.Polyline != nil:
+		points := o.Polyline.Points
+		var shapes []*cp.Shape
+		for i := 0; i+1 < len(points); i++ {
+			a, b := transform.Point(points[i]), transform.Point(points[i+1])
+			if a.Equal(b) {
+				continue
+			}
+			shapes = append(shapes, cp.NewSegment(body, a, b, 0))
+		}
+		return shapes, nil
+
+	case o.Width <= 0 || o.Height <= 0:
+		// nothing to collide with, like a rectangle that was never dragged out
+		return nil, nil
+
+	case o.Ellipse != nil:
+		rx, ry := o.Width/2, o.Height/2
+		if math.Abs(rx-ry) < 1e-6 {
+			return []*cp.Shape{cp.NewCircle(body, rx, transform.Point(cp.Vector{X: rx, Y: ry}))}, nil
+		}
+		return []*cp.Shape{poly(ellipse(o.Width, o.Height))}, nil
+
+	case o.GID != 0:
+		return []*cp.Shape{poly(box(0, -o.Height, o.Width, 0))}, nil
+
+	default:
+		return []*cp.Shape{poly(box(0, 0, o.Width, o.Height))}, nil
+	}
+}
+
+// massProperties shares mass evenly between the parts of a body, and finds its center of
+// gravity and moment of inertia.
+func massProperties(parts []*Object, mass float64) (cp.Vector, float64) {
+	share := mass / float64(len(parts))
+	centers := make([]cp.Vector, len(parts))
+	moments := make([]float64, len(parts))
+	var center cp.Vector
+	for i, part := range parts {
+		centers[i], moments[i] = part.massProperties(share)
+		center = center.Add(centers[i])
+	}
+	center = center.Mult(1 / float64(len(parts)))
+
+	var moment float64
+	for i := range parts {
+		moment += moments[i] + share*centers[i].DistanceSq(center)
+	}
+	return center, moment
+}
+
+// massProperties finds the object's center of gravity relative to the map, without layer
+// offsets, and its moment of inertia about it for the given mass.
+func (o *Object) massProperties(mass float64) (cp.Vector, float64) {
+	transform := cp.NewTransformRigid(cp.Vector{X: o.X, Y: o.Y}, o.Rotation*math.Pi/180)
+
+	var center cp.Vector
+	var moment float64
+	switch {
+	case o.Polygon != nil:
+		points := simplify(o.Polygon.Points)
+		if len(points) < 3 {
+			break
+		}
+		center = cp.CentroidForPoly(len(points), points)
+		moment = cp.MomentForPoly(mass, len(points), points, center.Neg(), 0)
+
+	case o.Polyline != nil:
+		// a chain of rods, each as heavy as it is long
+		points := o.Polyline.Points
+		var length float64
+		for i := 0; i+1 < len(points); i++ {
+			l := points[i].Distance(points[i+1])
+			center = center.Add(points[i].Lerp(points[i+1], 0.5).Mult(l))
+			length += l
+		}
+		if length == 0 {
+			break
+		}
+		center = center.Mult(1 / length)
+		for i := 0; i+1 < len(points); i++ {
+			m := mass * points[i].Distance(points[i+1]) / length
+			moment += cp.MomentForSegment(m, points[i].Sub(center), points[i+1].Sub(center), 0)
+		}
+
+	case o.Ellipse != nil:
+		rx, ry := o.Width/2, o.Height/2
+		center = cp.Vector{X: rx, Y: ry}
+		moment = mass * (rx*rx + ry*ry) / 4
+
+	case o.GID != 0:
+		center = cp.Vector{X: o.Width / 2, Y: -o.Height / 2}
+		moment = cp.MomentForBox(mass, o.Width, o.Height)
+
+	default:
+		center = cp.Vector{X: o.Width / 2, Y: o.Height / 2}
+		moment = cp.MomentForBox(mass, o.Width, o.Height)
+	}
+
+	if moment <= 0 {
+		// too thin to spin on its own, so give it the moment of a point mass a pixel out
+		moment = mass
+	}
+	return transform.Point(center), moment
+}
+
+// ellipse returns the polygon standing in for an ellipse that fills a w by h rectangle at the
+// origin.
+func ellipse(w, h float64) []cp.Vector {
+	rx, ry := w/2, h/2
+	points := make([]cp.Vector, ellipseSegments)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / ellipseSegments
+		points[i] = cp.Vector{X: rx + rx*math.Cos(angle), Y: ry + ry*math.Sin(angle)}
+	}
+	return points
+}
+
+func box(l, t, r, b float64) []cp.Vector {
+	return []cp.Vector{{X: l, Y: t}, {X: r, Y: t}, {X: r, Y: b}, {X: l, Y: b}}
+}
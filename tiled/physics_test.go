@@ -0,0 +1,233 @@
+package tiled
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// objectsMap has an object group moved by 10,20 holding one object of each kind, and a tile
+// with a collider over its left half placed as it is and flipped.
+const objectsMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="20" height="20" tilewidth="16" tileheight="16" infinite="0">
+ <tileset firstgid="1" name="solid" tilewidth="16" tileheight="16" tilecount="1" columns="1">
+  <tile id="0">
+   <objectgroup draworder="index">
+    <object id="1" x="0" y="0" width="8" height="16"/>
+   </objectgroup>
+  </tile>
+ </tileset>
+ <objectgroup id="1" name="collision" offsetx="10" offsety="20">
+  <properties>
+   <property name="friction" type="float" value="0.3"/>
+  </properties>
+  <object id="1" name="rect" x="0" y="0" width="20" height="10"/>
+  <object id="2" name="ball" x="40" y="0" width="10" height="10">
+   <properties>
+    <property name="body" value="dynamic"/>
+    <property name="mass" type="float" value="2"/>
+    <property name="elasticity" type="float" value="0.5"/>
+   </properties>
+   <ellipse/>
+  </object>
+  <object id="3" name="line" x="0" y="50">
+   <polyline points="0,0 10,0 10,10"/>
+  </object>
+  <object id="4" name="spawn" x="5" y="6">
+   <point/>
+  </object>
+  <object id="5" name="tile" gid="1" x="100" y="100" width="16" height="16"/>
+  <object id="6" name="flipped" gid="2147483649" x="200" y="100" width="16" height="16"/>
+ </objectgroup>
+</map>
+`
+
+func TestAddObjects(t *testing.T) {
+	m, err := Load(strings.NewReader(objectsMap), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := cp.NewSpace()
+	spawns, err := AddObjects(space, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spawns) != 1 || spawns[0].Object.Name != "spawn" || spawns[0].Position != (cp.Vector{X: 15, Y: 26}) {
+		t.Errorf("got spawns %+v, want spawn at 15,26", spawns)
+	}
+
+	type want struct {
+		class    string
+		bodyType int
+		bb       cp.BB
+	}
+	wants := map[string][]want{
+		"rect": {{"poly", cp.BODY_STATIC, cp.BB{L: 10, T: 30, R: 30, B: 20}}},
+		"ball": {{"circle", cp.BODY_DYNAMIC, cp.BB{L: 50, T: 30, R: 60, B: 20}}},
+		"line": {
+			{"segment", cp.BODY_STATIC, cp.BB{L: 10, T: 70, R: 20, B: 70}},
+			{"segment", cp.BODY_STATIC, cp.BB{L: 20, T: 80, R: 20, B: 70}},
+		},
+		// tile objects are placed by their bottom left corner
+		"tile":    {{"poly", cp.BODY_STATIC, cp.BB{L: 110, T: 120, R: 118, B: 104}}},
+		"flipped": {{"poly", cp.BODY_STATIC, cp.BB{L: 218, T: 120, R: 226, B: 104}}},
+	}
+
+	got := map[string][]want{}
+	space.EachShape(func(shape *cp.Shape) {
+		object, ok := shape.UserData.(*Object)
+		if !ok {
+			t.Errorf("shape has UserData %v, want its object", shape.UserData)
+			return
+		}
+		var class string
+		switch shape.Class.(type) {
+		case *cp.PolyShape:
+			class = "poly"
+		case *cp.Circle:
+			class = "circle"
+		case *cp.Segment:
+			class = "segment"
+		}
+		got[object.Name] = append(got[object.Name], want{class, shape.Body().GetType(), shape.CacheBB()})
+
+		friction := 0.3
+		if shape.Friction() != friction {
+			t.Errorf("%s has friction %v, want %v from its layer", object.Name, shape.Friction(), friction)
+		}
+	})
+
+	for name, shapes := range wants {
+		if len(got[name]) != len(shapes) {
+			t.Errorf("%s has %d shapes, want %d", name, len(got[name]), len(shapes))
+			continue
+		}
+		for i, w := range shapes {
+			g := got[name][i]
+			if g.class != w.class || g.bodyType != w.bodyType || !bbNear(g.bb, w.bb) {
+				t.Errorf("%s shape %d is %+v, want %+v", name, i, g, w)
+			}
+		}
+	}
+	if len(got) != len(wants) {
+		t.Errorf("got shapes for %d objects, want %d", len(got), len(wants))
+	}
+
+	var ball *cp.Body
+	space.EachBody(func(body *cp.Body) {
+		if body.GetType() == cp.BODY_DYNAMIC {
+			ball = body
+		}
+	})
+	if ball == nil || ball.UserData.(*Object).Name != "ball" {
+		t.Fatalf("got body %v, want one for the ball", ball)
+	}
+	if ball.Position() != (cp.Vector{X: 55, Y: 25}) || ball.Mass() != 2 {
+		t.Errorf("ball is at %v with mass %v, want 55,25 and 2", ball.Position(), ball.Mass())
+	}
+}
+
+func bbNear(a, b cp.BB) bool {
+	const epsilon = 1e-9
+	return math.Abs(a.L-b.L) < epsilon && math.Abs(a.T-b.T) < epsilon &&
+		math.Abs(a.R-b.R) < epsilon && math.Abs(a.B-b.B) < epsilon
+}
+
+func TestPhysicsFrom(t *testing.T) {
+	prop := func(pairs ...string) Properties {
+		var props Properties
+		for i := 0; i < len(pairs); i += 2 {
+			props = append(props, Property{Name: pairs[i], Value: pairs[i+1]})
+		}
+		return props
+	}
+
+	tests := []struct {
+		name  string
+		props Properties
+		want  physics
+		err   string
+	}{
+		{"defaults", nil, physics{bodyType: cp.BODY_STATIC, mass: 1, friction: 0.7, filter: cp.SHAPE_FILTER_ALL}, ""},
+		{"everything", prop("body", "kinematic", "friction", "0.1", "elasticity", "0.9", "sensor", "true",
+			"collisionType", "3", "filterGroup", "4", "filterCategories", "5", "filterMask", "6"),
+			physics{bodyType: cp.BODY_KINEMATIC, mass: 1, friction: 0.1, elasticity: 0.9, sensor: true,
+				collision: 3, filter: cp.ShapeFilter{Group: 4, Categories: 5, Mask: 6}}, ""},
+		{"own property first", prop("friction", "0.2", "friction", "0.4"),
+			physics{bodyType: cp.BODY_STATIC, mass: 1, friction: 0.2, filter: cp.SHAPE_FILTER_ALL}, ""},
+		{"unknown body", prop("body", "floppy"), physics{}, `unknown body type "floppy"`},
+		{"massless dynamic body", prop("body", "dynamic", "mass", "0"), physics{}, "positive mass"},
+		{"massless static body", prop("mass", "0"), physics{bodyType: cp.BODY_STATIC, friction: 0.7, filter: cp.SHAPE_FILTER_ALL}, ""},
+		{"bad number", prop("friction", "lots"), physics{}, "friction"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := physicsFrom(test.props)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != test.want {
+				t.Errorf("got %+v, want %+v", p, test.want)
+			}
+		})
+	}
+}
+
+func TestMirror(t *testing.T) {
+	// a collider on a 16x32 tile
+	rect := &Object{X: 2, Y: 4, Width: 6, Height: 8}
+	circle := &Object{X: 2, Y: 4, Width: 6, Height: 6, Ellipse: &struct{}{}}
+
+	tests := []struct {
+		name   string
+		object *Object
+		flags  uint32
+		// bb is the bounds of the mirrored outline, or of the circle
+		bb cp.BB
+	}{
+		{"unflipped", rect, 0, cp.BB{L: 2, T: 12, R: 8, B: 4}},
+		{"horizontal", rect, FlipHorizontal, cp.BB{L: 8, T: 12, R: 14, B: 4}},
+		{"vertical", rect, FlipVertical, cp.BB{L: 2, T: 28, R: 8, B: 20}},
+		{"both", rect, FlipHorizontal | FlipVertical, cp.BB{L: 8, T: 28, R: 14, B: 20}},
+		// turned a quarter clockwise, which Tiled stores as diagonal and horizontal, the tile
+		// is 32x16
+		{"turned", rect, FlipDiagonal | FlipHorizontal, cp.BB{L: 20, T: 8, R: 28, B: 2}},
+		{"circle", circle, FlipHorizontal | FlipVertical, cp.BB{L: 8, T: 28, R: 14, B: 22}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mirrored := test.object.mirror(test.flags, 16, 32)
+			if test.flags == 0 && mirrored != test.object {
+				t.Error("unflipped collider was copied")
+			}
+
+			var bb cp.BB
+			if mirrored.Polygon != nil {
+				points := mirrored.Polygon.Points
+				bb = cp.NewBBForExtents(points[0], 0, 0)
+				for _, p := range points {
+					bb = bb.Expand(p)
+				}
+			} else {
+				if mirrored.Ellipse == nil && test.object.Ellipse != nil {
+					t.Fatal("circle lost its ellipse")
+				}
+				bb = cp.BB{L: mirrored.X, T: mirrored.Y + mirrored.Height, R: mirrored.X + mirrored.Width, B: mirrored.Y}
+			}
+			if !bbNear(bb, test.bb) {
+				t.Errorf("got %+v, want %+v", bb, test.bb)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// Property is a custom property set in the editor. Type is one of string, int, float, bool,
+// color, file or object, and is empty for strings.
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func (p *Property) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type property Property
+	var decoded struct {
+		property
+		// multi-line strings are written as the element's text instead of the value
+		Text string `xml:",chardata"`
+	}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	*p = Property(decoded.property)
+	if p.Value == "" {
+		p.Value = decoded.Text
+	}
+	return nil
+}
+
+// Properties are the custom properties of a map, layer, tileset, tile or object.
+type Properties []Property
+
+// Get returns the value of a property and whether it is set.
+func (p Properties) Get(name string) (string, bool) {
+	for _, prop := range p {
+		if prop.Name == name {
+			return prop.Value, true
+		}
+	}
+	return "", false
+}
+
+// String returns the value of a property, or def if it isn't set.
+func (p Properties) String(name, def string) string {
+	if v, ok := p.Get(name); ok {
+		return v
+	}
+	return def
+}
+
+// Float returns the value of a property as a number, or def if it isn't set.
+func (p Properties) Float(name string, def float64) (float64, error) {
+	v, ok := p.Get(name)
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def, fmt.Errorf("property %q: %w", name, err)
+	}
+	return f, nil
+}
+
+// Int returns the value of a property as an integer, or def if it isn't set.
+func (p Properties) Int(name string, def int) (int, error) {
+	v, ok := p.Get(name)
+	if !ok {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return def, fmt.Errorf("property %q: %w", name, err)
+	}
+	return i, nil
+}
+
+// Uint returns the value of a property as an unsigned integer, or def if it isn't set. Like
+// in Go, a 0x or 0b prefix is read as hexadecimal or binary, which suits bit masks.
+func (p Properties) Uint(name string, def uint) (uint, error) {
+	v, ok := p.Get(name)
+	if !ok {
+		return def, nil
+	}
+	u, err := strconv.ParseUint(v, 0, strconv.IntSize)
+	if err != nil {
+		return def, fmt.Errorf("property %q: %w", name, err)
+	}
+	return uint(u), nil
+}
+
+// Bool returns the value of a property as a boolean, or def if it isn't set.
+func (p Properties) Bool(name string, def bool) (bool, error) {
+	v, ok := p.Get(name)
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, fmt.Errorf("property %q: %w", name, err)
+	}
+	return b, nil
+}
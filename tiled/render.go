@@ -0,0 +1,348 @@
+//go:build !headless
+
+package tiled
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// Renderer draws the tile and image layers of an orthogonal map with Ebiten.
+type Renderer struct {
+	Map *Map
+
+	tiles      map[uint32]*ebiten.Image
+	images     map[*Image]*ebiten.Image
+	animations map[uint32]animation
+
+	// time is how long animations have been playing for, in seconds
+	time float64
+
+	// overhangX and overhangY are how far in pixels the biggest tile sticks out of its cell,
+	// to the right and up
+	overhangX, overhangY int
+
+	blocks map[blockKey]*block
+	// frame counts calls to Draw, to tell which blocks haven't been seen for a while
+	frame int
+}
+
+// animation is the frames of an animated tile as global tile ids, and when each one ends in
+// seconds from the start.
+type animation struct {
+	frames []uint32
+	ends   []float64
+}
+
+// NewRenderer loads the images of every tileset and image layer in the map.
+func NewRenderer(m *Map) (*Renderer, error) {
+	r := &Renderer{
+		Map:        m,
+		tiles:      map[uint32]*ebiten.Image{},
+		images:     map[*Image]*ebiten.Image{},
+		animations: map[uint32]animation{},
+		blocks:     map[blockKey]*block{},
+	}
+
+	for _, ts := range m.TileSets {
+		for _, tile := range ts.Tiles {
+			if len(tile.Animation) == 0 {
+				continue
+			}
+			var a animation
+			var end float64
+			for _, frame := range tile.Animation {
+				end += float64(frame.Duration) / 1000
+				a.frames = append(a.frames, ts.FirstGID+uint32(frame.TileID))
+				a.ends = append(a.ends, end)
+			}
+			r.animations[ts.FirstGID+uint32(tile.ID)] = a
+		}
+
+		if ts.Image == nil {
+			// a collection of images, one per tile
+			for _, tile := range ts.Tiles {
+				if tile.Image == nil {
+					continue
+				}
+				img, err := loadImage(ts.Path(tile.Image), tile.Image.Trans)
+				if err != nil {
+					return nil, err
+				}
+				r.tiles[ts.FirstGID+uint32(tile.ID)] = img
+			}
+			continue
+		}
+
+		img, err := loadImage(ts.Path(ts.Image), ts.Image.Trans)
+		if err != nil {
+			return nil, err
+		}
+		for id := 0; id < ts.TileCount; id++ {
+			r.tiles[ts.FirstGID+uint32(id)] = img.SubImage(ts.TileRect(id)).(*ebiten.Image)
+		}
+	}
+
+	for _, img := range r.tiles {
+		// a tile flipped diagonally is on its side, so either side can end up either way
+		w, h := img.Size()
+		size := w
+		if h > size {
+			size = h
+		}
+		if size-m.TileWidth > r.overhangX {
+			r.overhangX = size - m.TileWidth
+		}
+		if size-m.TileHeight > r.overhangY {
+			r.overhangY = size - m.TileHeight
+		}
+	}
+
+	for _, layer := range m.AllLayers() {
+		if layer.Kind != ImageLayer || layer.Image == nil || layer.Image.Source == "" {
+			continue
+		}
+		img, err := loadImage(m.Path(layer.Image), layer.Image.Trans)
+		if err != nil {
+			return nil, err
+		}
+		r.images[layer.Image] = img
+	}
+
+	return r, nil
+}
+
+// Update moves animated tiles on by dt seconds.
+func (r *Renderer) Update(dt float64) {
+	r.time += dt
+}
+
+// Tile returns the image of a global tile id, or of the frame it is on if it is animated. It
+// returns nil for an empty or unknown tile. Flip flags are ignored.
+func (r *Renderer) Tile(gid uint32) *ebiten.Image {
+	gid, _ = SplitGID(gid)
+	if a, ok := r.animations[gid]; ok {
+		gid = a.frame(r.time)
+	}
+	return r.tiles[gid]
+}
+
+// frame finds the frame shown at a time, looping forever.
+func (a animation) frame(time float64) uint32 {
+	total := a.ends[len(a.ends)-1]
+	if total <= 0 {
+		return a.frames[0]
+	}
+	time = math.Mod(time, total)
+	for i, end := range a.ends {
+		if time < end {
+			return a.frames[i]
+		}
+	}
+	return a.frames[len(a.frames)-1]
+}
+
+// layerState is what a layer inherits from the groups it is in.
+type layerState struct {
+	offsetX, offsetY     float64
+	opacity              float64
+	parallaxX, parallaxY float64
+}
+
+// Draw draws the visible tile and image layers in order. view maps map pixels to the screen,
+// like a camera; layers with parallax are shifted by how far the center of the screen is
+// from the map's parallax origin.
+//
+// Only the tiles that can be on screen are drawn. Tile layers are drawn ahead of time in
+// blocks of 16 by 16 tiles, except for animated tiles, and the blocks are kept while they
+// are on screen and for a second after. Call Invalidate after changing the tiles of a layer.
+func (r *Renderer) Draw(screen *ebiten.Image, view ebiten.GeoM) {
+	r.frame++
+
+	centerX, centerY := r.Map.ParallaxOriginX, r.Map.ParallaxOriginY
+	if view.IsInvertible() {
+		inverse := view
+		inverse.Invert()
+		w, h := screen.Size()
+		centerX, centerY = inverse.Apply(float64(w)/2, float64(h)/2)
+	}
+
+	state := layerState{opacity: 1, parallaxX: 1, parallaxY: 1}
+	r.drawLayers(screen, r.Map.Layers, view, centerX, centerY, state)
+
+	if r.frame%blockLifetime == 0 {
+		r.forget(r.frame - blockLifetime)
+	}
+}
+
+func (r *Renderer) drawLayers(screen *ebiten.Image, layers []*Layer, view ebiten.GeoM, centerX, centerY float64, parent layerState) {
+	for _, layer := range layers {
+		if !layer.Visible {
+			continue
+		}
+
+		state := layerState{
+			offsetX:   parent.offsetX + layer.OffsetX,
+			offsetY:   parent.offsetY + layer.OffsetY,
+			opacity:   parent.opacity * layer.Opacity,
+			parallaxX: parent.parallaxX * layer.ParallaxX,
+			parallaxY: parent.parallaxY * layer.ParallaxY,
+		}
+
+		var geoM ebiten.GeoM
+		geoM.Translate(
+			state.offsetX+(centerX-r.Map.ParallaxOriginX)*(1-state.parallaxX),
+			state.offsetY+(centerY-r.Map.ParallaxOriginY)*(1-state.parallaxY),
+		)
+		geoM.Concat(view)
+
+		switch layer.Kind {
+		case TileLayer:
+			r.drawTileLayer(screen, layer, geoM, state.opacity)
+		case ImageLayer:
+			if img := r.images[layer.Image]; img != nil {
+				op := &ebiten.DrawImageOptions{GeoM: geoM}
+				op.ColorM.Scale(1, 1, 1, state.opacity)
+				screen.DrawImage(img, op)
+			}
+		case GroupLayer:
+			r.drawLayers(screen, layer.Layers, view, centerX, centerY, state)
+		}
+	}
+}
+
+func (r *Renderer) drawTileLayer(screen *ebiten.Image, layer *Layer, geoM ebiten.GeoM, opacity float64) {
+	visible := r.visibleTiles(screen, geoM).Intersect(layer.Bounds())
+	if visible.Empty() {
+		return
+	}
+
+	tileWidth, tileHeight := float64(r.Map.TileWidth), float64(r.Map.TileHeight)
+	op := &ebiten.DrawImageOptions{}
+	op.ColorM.Scale(1, 1, 1, opacity)
+	for by := floorDiv(visible.Min.Y, blockSize); by*blockSize < visible.Max.Y; by++ {
+		for bx := floorDiv(visible.Min.X, blockSize); bx*blockSize < visible.Max.X; bx++ {
+			b := r.block(layer, bx, by)
+			if b.image != nil {
+				op.GeoM.Reset()
+				op.GeoM.Translate(float64(bx*blockSize)*tileWidth, float64(by*blockSize)*tileHeight-float64(r.overhangY))
+				op.GeoM.Concat(geoM)
+				screen.DrawImage(b.image, op)
+			}
+			for _, t := range b.animated {
+				img := r.Tile(t.gid)
+				if img == nil {
+					continue
+				}
+				op.GeoM = r.placeTile(img, t.gid, t.x, t.y)
+				op.GeoM.Concat(geoM)
+				screen.DrawImage(img, op)
+			}
+		}
+	}
+}
+
+// visibleTiles returns the cells of a layer drawn with geoM that the tiles on screen are in,
+// including those of tiles that stick out of their cell onto the screen.
+func (r *Renderer) visibleTiles(screen *ebiten.Image, geoM ebiten.GeoM) image.Rectangle {
+	if !geoM.IsInvertible() {
+		return image.Rectangle{}
+	}
+	geoM.Invert()
+	w, h := screen.Size()
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]int{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		x, y := geoM.Apply(float64(corner[0]), float64(corner[1]))
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+
+	tileWidth, tileHeight := float64(r.Map.TileWidth), float64(r.Map.TileHeight)
+	return image.Rect(
+		int(math.Floor((minX-float64(r.overhangX))/tileWidth)),
+		int(math.Floor(minY/tileHeight)),
+		int(math.Ceil(maxX/tileWidth)),
+		int(math.Ceil((maxY+float64(r.overhangY))/tileHeight)),
+	)
+}
+
+// placeTile returns where a tile's image goes in map pixels, flipped according to its flags.
+func (r *Renderer) placeTile(img *ebiten.Image, gid uint32, x, y int) ebiten.GeoM {
+	_, flags := SplitGID(gid)
+	w, h := img.Size()
+	geoM := flip(flags, float64(w), float64(h))
+	if flags&FlipDiagonal != 0 {
+		h = w
+	}
+	// tiles bigger than the grid stick up from the bottom left of their cell
+	geoM.Translate(float64(x*r.Map.TileWidth), float64((y+1)*r.Map.TileHeight-h))
+	return geoM
+}
+
+// flip mirrors a tile image of the given size in place according to its flip flags.
+func flip(flags uint32, w, h float64) ebiten.GeoM {
+	var m ebiten.GeoM
+	if flags&FlipDiagonal != 0 {
+		// swap x and y by turning a quarter anticlockwise and mirroring vertically
+		m.Rotate(-math.Pi / 2)
+		m.Scale(1, -1)
+		w, h = h, w
+	}
+	if flags&FlipHorizontal != 0 {
+		m.Scale(-1, 1)
+		m.Translate(w, 0)
+	}
+	if flags&FlipVertical != 0 {
+		m.Scale(1, -1)
+		m.Translate(0, h)
+	}
+	return m
+}
+
+func loadImage(path, trans string) (*ebiten.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if trans != "" {
+		key, err := strconv.ParseUint(strings.TrimPrefix(trans, "#"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad transparent color %q", path, trans)
+		}
+		img = keyOut(img, color.RGBA{R: uint8(key >> 16), G: uint8(key >> 8), B: uint8(key), A: 0xff})
+	}
+
+	return ebiten.NewImageFromImage(img), nil
+}
+
+// keyOut makes every pixel of the given color transparent.
+func keyOut(img image.Image, key color.RGBA) image.Image {
+	out := image.NewNRGBA(img.Bounds())
+	draw.Draw(out, out.Rect, img, img.Bounds().Min, draw.Src)
+	for i := 0; i < len(out.Pix); i += 4 {
+		if out.Pix[i] == key.R && out.Pix[i+1] == key.G && out.Pix[i+2] == key.B {
+			out.Pix[i+3] = 0
+		}
+	}
+	return out
+}
@@ -0,0 +1,151 @@
+package tiled
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/jakecoffman/cp"
+	"github.com/jakecoffman/cpebiten"
+)
+
+// AddSolidTiles adds static walls around the solid tiles of every tile layer, hidden or not.
+// A tile is solid if its "solid" property is true, looked up on the tile and then on the
+// layers it is in, so a whole layer can be made solid and single tiles left out.
+//
+// Rather than a box per tile, the solid tiles of a layer are merged and only the outline of
+// each solid area is added, as segments as long as they can be. Bodies sliding along a floor
+// or wall then have no seams between tiles to catch on, and a big map needs far fewer
+// shapes. In an infinite map the walls are split where the layer's chunks meet. The
+// segments' material comes from the layer's properties as for AddObjects, and their UserData
+// is the layer.
+//
+// The segments have a radius of 1, or of the layer's "wallRadius" property, because fast
+// bodies can pass through a segment with no thickness from one step to the next. The walls
+// stick out from the tiles by that much.
+func AddSolidTiles(space *cp.Space, m *Map) error {
+	into := &loaded{space: space}
+	return walkLayers(m.Layers, cp.Vector{}, nil, func(layer *Layer, offset cp.Vector, props Properties) error {
+		if layer.Kind != TileLayer {
+			return nil
+		}
+		for _, chunk := range layer.Chunks {
+			if err := addSolidTiles(into, m, layer, chunk, offset, props); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// defaultWallRadius is how thick the walls around solid tiles are unless a layer says otherwise.
+const defaultWallRadius = 1
+
+// addSolidTiles adds the walls around the solid tiles in a chunk of a layer. Walls along the
+// edge of the chunk are only added on the side of the solid tiles, so chunks of the same
+// layer can be added and removed independently, but walls are split where chunks meet.
+func addSolidTiles(into *loaded, m *Map, layer *Layer, chunk *Chunk, offset cp.Vector, props Properties) error {
+	// whether a tile is solid only depends on its id, so remember it
+	known := map[uint32]bool{}
+	var err error
+	solid := func(x, y int) bool {
+		gid, _ := SplitGID(layer.TileAt(x, y))
+		if gid == 0 {
+			return false
+		}
+		isSolid, ok := known[gid]
+		if ok {
+			return isSolid
+		}
+		tileProps := props
+		if ts, id := m.TileSetFor(gid); ts != nil {
+			if tile := ts.Tile(id); tile != nil {
+				tileProps = inherit(tile.Properties, props)
+			}
+		}
+		isSolid, e := tileProps.Bool("solid", false)
+		if e != nil && err == nil {
+			err = fmt.Errorf("tile at %d,%d: %w", x, y, e)
+		}
+		known[gid] = isSolid
+		return isSolid
+	}
+
+	edges := outline(solid, chunk.Bounds())
+	if err != nil {
+		return err
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+
+	material, err := physicsFrom(props)
+	if err != nil {
+		return err
+	}
+	radius, err := props.Float("wallRadius", defaultWallRadius)
+	if err != nil {
+		return err
+	}
+	if radius < 0 {
+		return fmt.Errorf("property %q: a wall can't have a negative radius", "wallRadius")
+	}
+	tileWidth, tileHeight := float64(m.TileWidth), float64(m.TileHeight)
+	for _, edge := range edges {
+		a := offset.Add(cp.Vector{X: edge[0].X * tileWidth, Y: edge[0].Y * tileHeight})
+		b := offset.Add(cp.Vector{X: edge[1].X * tileWidth, Y: edge[1].Y * tileHeight})
+		shape := cpebiten.AddWall(into.space, into.space.StaticBody, a, b, radius)
+		material.apply(shape)
+		shape.UserData = layer
+		into.shapes = append(into.shapes, shape)
+	}
+	return nil
+}
+
+// outline returns the edges between solid and empty cells that belong to the solid cells
+// within bounds, in cells, with the edges along the same line joined into one where they
+// meet.
+func outline(solid func(x, y int) bool, bounds image.Rectangle) [][2]cp.Vector {
+	// edge reports whether there is an edge between two neighboring cells that belongs to
+	// one of them that is inside bounds
+	edge := func(x0, y0, x1, y1 int) bool {
+		a, b := solid(x0, y0), solid(x1, y1)
+		if a == b {
+			return false
+		}
+		if a {
+			return image.Pt(x0, y0).In(bounds)
+		}
+		return image.Pt(x1, y1).In(bounds)
+	}
+
+	var edges [][2]cp.Vector
+	// horizontal edges, along the top of each row and the bottom of the last
+	for y := bounds.Min.Y; y <= bounds.Max.Y; y++ {
+		start, running := 0, false
+		for x := bounds.Min.X; x <= bounds.Max.X; x++ {
+			if x < bounds.Max.X && edge(x, y-1, x, y) {
+				if !running {
+					start, running = x, true
+				}
+			} else if running {
+				edges = append(edges, [2]cp.Vector{{X: float64(start), Y: float64(y)}, {X: float64(x), Y: float64(y)}})
+				running = false
+			}
+		}
+	}
+	// vertical edges, along the left of each column and the right of the last
+	for x := bounds.Min.X; x <= bounds.Max.X; x++ {
+		start, running := 0, false
+		for y := bounds.Min.Y; y <= bounds.Max.Y; y++ {
+			if y < bounds.Max.Y && edge(x-1, y, x, y) {
+				if !running {
+					start, running = y, true
+				}
+			} else if running {
+				edges = append(edges, [2]cp.Vector{{X: float64(x), Y: float64(start)}, {X: float64(x), Y: float64(y)}})
+				running = false
+			}
+		}
+	}
+	return edges
+}
@@ -0,0 +1,85 @@
+package tiled
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// grid reads solid cells from rows of text, where # is solid. Everything outside is empty.
+func grid(rows ...string) func(x, y int) bool {
+	return func(x, y int) bool {
+		return y >= 0 && y < len(rows) && x >= 0 && x < len(rows[y]) && rows[y][x] == '#'
+	}
+}
+
+func edge(x0, y0, x1, y1 float64) [2]cp.Vector {
+	return [2]cp.Vector{{X: x0, Y: y0}, {X: x1, Y: y1}}
+}
+
+func TestOutline(t *testing.T) {
+	tests := []struct {
+		name   string
+		rows   []string
+		bounds image.Rectangle
+		// edges are the horizontal edges from top to bottom, then the vertical ones from left
+		// to right
+		edges [][2]cp.Vector
+	}{
+		{"empty", []string{"..", ".."}, image.Rect(0, 0, 2, 2), nil},
+		{"single tile", []string{"#"}, image.Rect(0, 0, 1, 1), [][2]cp.Vector{
+			edge(0, 0, 1, 0), edge(0, 1, 1, 1),
+			edge(0, 0, 0, 1), edge(1, 0, 1, 1),
+		}},
+		{"row", []string{"###"}, image.Rect(0, 0, 3, 1), [][2]cp.Vector{
+			edge(0, 0, 3, 0), edge(0, 1, 3, 1),
+			edge(0, 0, 0, 1), edge(3, 0, 3, 1),
+		}},
+		{"L-shape", []string{
+			"#.",
+			"##",
+		}, image.Rect(0, 0, 2, 2), [][2]cp.Vector{
+			edge(0, 0, 1, 0), edge(1, 1, 2, 1), edge(0, 2, 2, 2),
+			edge(0, 0, 0, 2), edge(1, 0, 1, 1), edge(2, 1, 2, 2),
+		}},
+		{"hole", []string{
+			"###",
+			"#.#",
+			"###",
+		}, image.Rect(0, 0, 3, 3), [][2]cp.Vector{
+			edge(0, 0, 3, 0), edge(1, 1, 2, 1), edge(1, 2, 2, 2), edge(0, 3, 3, 3),
+			edge(0, 0, 0, 3), edge(1, 1, 1, 2), edge(2, 1, 2, 2), edge(3, 0, 3, 3),
+		}},
+		// the edges of tiles that only touch at a corner meet there, so they are joined
+		{"touching corners", []string{
+			"#.",
+			".#",
+		}, image.Rect(0, 0, 2, 2), [][2]cp.Vector{
+			edge(0, 0, 1, 0), edge(0, 1, 2, 1), edge(1, 2, 2, 2),
+			edge(0, 0, 0, 1), edge(1, 0, 1, 2), edge(2, 1, 2, 2),
+		}},
+		// a chunk of a larger map gets the edges of its own tiles and none where it meets a
+		// solid tile in the next chunk
+		{"bounds", []string{
+			"###",
+			"#..",
+		}, image.Rect(1, 0, 3, 2), [][2]cp.Vector{
+			edge(1, 0, 3, 0), edge(1, 1, 3, 1),
+			edge(3, 0, 3, 1),
+		}},
+		{"outside bounds", []string{
+			"#..",
+		}, image.Rect(1, 0, 3, 1), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edges := outline(grid(test.rows...), test.bounds)
+			if !reflect.DeepEqual(edges, test.edges) {
+				t.Errorf("got %v, want %v", edges, test.edges)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package tiled
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jakecoffman/cp"
+)
+
+// Streamer keeps the colliders of the tiles near the camera in a space, for maps too big to
+// have all of them in at once, like infinite ones. It does what AddTileColliders and
+// AddSolidTiles do, a chunk of a tile layer at a time: as the view moves, the chunks that come
+// near it are added and the ones it leaves far behind are removed. A layer of a map that isn't
+// infinite is a single chunk. Objects aren't streamed, so add them once with AddObjects.
+//
+// Update has to be called between steps of the space, not from a callback during one.
+type Streamer struct {
+	Space *cp.Space
+	Map   *Map
+	// Margin is how far around the view, in map pixels, chunks are added. Chunks are removed
+	// once they are twice as far, so going back and forth over the edge of one doesn't keep
+	// adding and removing it.
+	Margin float64
+
+	layers []streamedLayer
+	chunks map[*Chunk]*loaded
+}
+
+// streamedLayer is a tile layer with what it inherits from the groups it is in.
+type streamedLayer struct {
+	layer  *Layer
+	offset cp.Vector
+	props  Properties
+}
+
+// NewStreamer creates a streamer for the tile layers of a map, with a margin of four tiles.
+// Nothing is added to the space until Update is called.
+func NewStreamer(space *cp.Space, m *Map) *Streamer {
+	s := &Streamer{
+		Space:  space,
+		Map:    m,
+		Margin: 4 * math.Max(float64(m.TileWidth), float64(m.TileHeight)),
+		chunks: map[*Chunk]*loaded{},
+	}
+	_ = walkLayers(m.Layers, cp.Vector{}, nil, func(layer *Layer, offset cp.Vector, props Properties) error {
+		if layer.Kind == TileLayer {
+			s.layers = append(s.layers, streamedLayer{layer, offset, props})
+		}
+		return nil
+	})
+	return s
+}
+
+// Update adds and removes chunks for a view, the part of the map that can be seen in map
+// pixels.
+func (s *Streamer) Update(view cp.BB) error {
+	near, far := grow(view, s.Margin), grow(view, 2*s.Margin)
+	tileWidth, tileHeight := float64(s.Map.TileWidth), float64(s.Map.TileHeight)
+
+	for _, l := range s.layers {
+		for _, chunk := range l.layer.Chunks {
+			bb := cp.BB{
+				L: l.offset.X + float64(chunk.X)*tileWidth,
+				B: l.offset.Y + float64(chunk.Y)*tileHeight,
+				R: l.offset.X + float64(chunk.X+chunk.Width)*tileWidth,
+				T: l.offset.Y + float64(chunk.Y+chunk.Height)*tileHeight,
+			}
+
+			into, ok := s.chunks[chunk]
+			switch {
+			case !ok && bb.Intersects(near):
+				into = &loaded{space: s.Space}
+				if err := s.add(into, l, chunk); err != nil {
+					into.remove()
+					return fmt.Errorf("layer %q: chunk at %d,%d: %w", l.layer.Name, chunk.X, chunk.Y, err)
+				}
+				s.chunks[chunk] = into
+			case ok && !bb.Intersects(far):
+				into.remove()
+				delete(s.chunks, chunk)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Streamer) add(into *loaded, l streamedLayer, chunk *Chunk) error {
+	if err := addTileColliders(into, s.Map, chunk, l.offset, l.props); err != nil {
+		return err
+	}
+	return addSolidTiles(into, s.Map, l.layer, chunk, l.offset, l.props)
+}
+
+// Loaded returns how many chunks are in the space.
+func (s *Streamer) Loaded() int {
+	return len(s.chunks)
+}
+
+// Clear removes every chunk from the space.
+func (s *Streamer) Clear() {
+	for chunk, into := range s.chunks {
+		into.remove()
+		delete(s.chunks, chunk)
+	}
+}
+
+// grow returns bb made bigger by margin on every side.
+func grow(bb cp.BB, margin float64) cp.BB {
+	return cp.BB{L: bb.L - margin, B: bb.B - margin, R: bb.R + margin, T: bb.T + margin}
+}
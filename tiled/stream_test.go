@@ -0,0 +1,109 @@
+package tiled
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jakecoffman/cp"
+)
+
+// streamMap is an infinite map with a solid layer of two 4x4 chunks side by side, 64 pixels
+// each, that are full of tiles. Each chunk gets walls on the three sides that aren't against
+// the other one.
+const streamMap = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="8" height="4" tilewidth="16" tileheight="16" infinite="1">
+ <tileset firstgid="1" name="solid" tilewidth="16" tileheight="16" tilecount="1" columns="1"/>
+ <layer id="1" name="ground" width="8" height="4">
+  <properties>
+   <property name="solid" type="bool" value="true"/>
+  </properties>
+  <data encoding="csv">
+   <chunk x="0" y="0" width="4" height="4">
+1,1,1,1,
+1,1,1,1,
+1,1,1,1,
+1,1,1,1
+</chunk>
+   <chunk x="4" y="0" width="4" height="4">
+1,1,1,1,
+1,1,1,1,
+1,1,1,1,
+1,1,1,1
+</chunk>
+  </data>
+ </layer>
+</map>
+`
+
+func TestStreamer(t *testing.T) {
+	m, err := Load(strings.NewReader(streamMap), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := cp.NewSpace()
+	s := NewStreamer(space, m)
+	s.Margin = 16
+
+	// view returns a view 20 pixels wide from x over the height of the map
+	view := func(x float64) cp.BB {
+		return cp.BB{L: x, B: 0, R: x + 20, T: 64}
+	}
+
+	// the chunks span 0 to 64 and 64 to 128 across
+	steps := []struct {
+		name   string
+		x      float64
+		loaded int
+	}{
+		{"far to the left", -200, 0},
+		{"left chunk within the margin", -30, 1},
+		{"over the left chunk", 20, 1},
+		// the left chunk is further than the margin but not twice as far, so it stays
+		{"right chunk within the margin", 85, 2},
+		{"left chunk twice the margin behind", 100, 1},
+		{"back over the left chunk", 30, 2},
+		{"far to the right", 300, 0},
+	}
+	for _, step := range steps {
+		if err := s.Update(view(step.x)); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if s.Loaded() != step.loaded {
+			t.Errorf("%s: %d chunks loaded, want %d", step.name, s.Loaded(), step.loaded)
+		}
+		if shapes := countShapes(space); shapes != 3*step.loaded {
+			t.Errorf("%s: %d shapes in the space, want %d", step.name, shapes, 3*step.loaded)
+		}
+	}
+
+	// where the first streamer kept the left chunk, a new one doesn't add it
+	fresh := NewStreamer(cp.NewSpace(), m)
+	fresh.Margin = 16
+	if err := fresh.Update(view(85)); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Loaded() != 1 {
+		t.Errorf("new streamer loaded %d chunks, want only the right one", fresh.Loaded())
+	}
+
+	if err := s.Update(view(50)); err != nil {
+		t.Fatal(err)
+	}
+	space.EachShape(func(shape *cp.Shape) {
+		if shape.Body() != space.StaticBody || shape.UserData != m.Layers[0] {
+			t.Errorf("shape on %v with UserData %v, want a static wall of the layer", shape.Body(), shape.UserData)
+		}
+	})
+	s.Clear()
+	if s.Loaded() != 0 || countShapes(space) != 0 {
+		t.Errorf("after Clear %d chunks and %d shapes remain", s.Loaded(), countShapes(space))
+	}
+}
+
+func countShapes(space *cp.Space) int {
+	var n int
+	space.EachShape(func(*cp.Shape) {
+		n++
+	})
+	return n
+}
@@ -1,317 +1,173 @@
-package main
+// Package tiled loads maps made with the Tiled map editor (https://www.mapeditor.org) from
+// TMX and TSX files into a typed model.
+//
+// The demo in tiled/demo shows a map drawn with Ebiten and its objects turned into physics
+// shapes. Run it from the root of the repository with go run ./tiled/demo.
+package tiled
 
 import (
 	"encoding/xml"
 	"fmt"
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
-	"github.com/jakecoffman/cp"
-	"github.com/jakecoffman/cpebiten"
-	"golang.org/x/image/math/f64"
-	"image"
-	"image/color"
-	"log"
-	"math"
+	"io"
 	"os"
-	"strconv"
-	"strings"
-
-	_ "image/png"
+	"path/filepath"
 )
 
-type Game struct {
-	Game    *cpebiten.Game
-	map1    Map
-	tileSet map[int]image.Image
-
-	world  *ebiten.Image
-	camera Camera
-
-	drawPhysics bool
-}
-
+// Map is a TMX document.
 type Map struct {
-	MapLayer [][]int // parsed tiles from the tile layer
-
-	Width      int `xml:"width,attr"`
-	Height     int `xml:"height,attr"`
-	TileWidth  int `xml:"tilewidth,attr"`
-	TileHeight int `xml:"tileheight,attr"`
-	TileSets   []struct {
-		Text     string `xml:",chardata"`
-		Firstgid int    `xml:"firstgid,attr"`
-		Source   string `xml:"source,attr"`
-	} `xml:"tileset"`
-	Layer struct {
-		Text   string `xml:",chardata"`
-		ID     int    `xml:"id,attr"`
-		Name   string `xml:"name,attr"`
-		Width  int    `xml:"width,attr"`
-		Height int    `xml:"height,attr"`
-		Data   struct {
-			Text string `xml:",chardata"`
-		} `xml:"data"`
-	} `xml:"layer"`
-	ObjectGroups struct {
-		Text   string `xml:",chardata"`
-		ID     string `xml:"id,attr"`
-		Name   string `xml:"name,attr"`
-		Object []struct {
-			Text   string  `xml:",chardata"`
-			ID     int     `xml:"id,attr"`
-			X      float64 `xml:"x,attr"`
-			Y      float64 `xml:"y,attr"`
-			Width  float64 `xml:"width,attr"`
-			Height float64 `xml:"height,attr"`
-		} `xml:"object"`
-	} `xml:"objectgroup"`
-}
-
-type TileSet struct {
-	Name  string `xml:"name,attr"`
-	Image struct {
-		Source string `xml:"source,attr"`
-		Width  int    `xml:"width,attr"`
-		Height int    `xml:"height,attr"`
-	} `xml:"image"`
+	Version         string `xml:"version,attr"`
+	TiledVersion    string `xml:"tiledversion,attr"`
+	Orientation     string `xml:"orientation,attr"`
+	RenderOrder     string `xml:"renderorder,attr"`
+	Width           int    `xml:"width,attr"`
+	Height          int    `xml:"height,attr"`
+	TileWidth       int    `xml:"tilewidth,attr"`
+	TileHeight      int    `xml:"tileheight,attr"`
+	Infinite        bool   `xml:"infinite,attr"`
+	BackgroundColor string `xml:"backgroundcolor,attr"`
+	NextLayerID     int    `xml:"nextlayerid,attr"`
+	NextObjectID    int    `xml:"nextobjectid,attr"`
+	// ParallaxOriginX and ParallaxOriginY are where the camera has to be looking for layers
+	// with parallax to be drawn at their normal position.
+	ParallaxOriginX float64 `xml:"parallaxoriginx,attr"`
+	ParallaxOriginY float64 `xml:"parallaxoriginy,attr"`
+
+	Properties Properties `xml:"properties>property"`
+
+	// TileSets are sorted by FirstGID, as Tiled writes them. External tilesets are loaded
+	// in place of their <tileset source="..."> reference.
+	TileSets []*TileSet `xml:"tileset"`
+
+	// Layers are in the order they are drawn, bottom first.
+	Layers layerList `xml:",any"`
+
+	// dir is where tilesets and images are relative to
+	dir string
 }
 
-type Camera struct {
-	ViewPort   f64.Vec2
-	Position   f64.Vec2
-	ZoomFactor int
-	Rotation   int
-}
+// The high bits of a global tile id placed in a layer or on a tile object say how the tile is
+// mirrored. Turning a tile in Tiled is stored as a combination of them.
+const (
+	FlipHorizontal uint32 = 0x80000000
+	FlipVertical   uint32 = 0x40000000
+	// FlipDiagonal swaps x and y, before the other flips are applied.
+	FlipDiagonal uint32 = 0x20000000
+	// rotateHexagonal120 is only used by hexagonal maps, but is stripped all the same.
+	rotateHexagonal120 uint32 = 0x10000000
+
+	flipFlags = FlipHorizontal | FlipVertical | FlipDiagonal | rotateHexagonal120
+)
 
-func (c *Camera) String() string {
-	return fmt.Sprintf(
-		"T: %.1f, R: %d, S: %d",
-		c.Position, c.Rotation, c.ZoomFactor,
-	)
+// SplitGID separates a global tile id as placed in a map into the id of the tile and its flip
+// flags.
+func SplitGID(gid uint32) (uint32, uint32) {
+	return gid &^ flipFlags, gid & flipFlags
 }
 
-func (c *Camera) viewportCenter() f64.Vec2 {
-	return f64.Vec2{
-		c.ViewPort[0] * 0.5,
-		c.ViewPort[1] * 0.5,
+// LoadFile reads a TMX file. External tilesets are read relative to it.
+func LoadFile(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("tiled: %w", err)
 	}
-}
-
-func (c *Camera) worldMatrix() ebiten.GeoM {
-	m := ebiten.GeoM{}
-	m.Translate(-c.Position[0], -c.Position[1])
-	// We want to scale and rotate around center of image / screen
-	m.Translate(-c.viewportCenter()[0], -c.viewportCenter()[1])
-	m.Scale(
-		math.Pow(1.01, float64(c.ZoomFactor)),
-		math.Pow(1.01, float64(c.ZoomFactor)),
-	)
-	m.Rotate(float64(c.Rotation) * 2 * math.Pi / 360)
-	m.Translate(c.viewportCenter()[0], c.viewportCenter()[1])
-	return m
-}
-
-func (c *Camera) Render(world, screen *ebiten.Image) {
-	screen.DrawImage(world, &ebiten.DrawImageOptions{
-		GeoM: c.worldMatrix(),
-	})
-}
+	defer f.Close()
 
-func (c *Camera) ScreenToWorld(posX, posY int) (float64, float64) {
-	inverseMatrix := c.worldMatrix()
-	if inverseMatrix.IsInvertible() {
-		inverseMatrix.Invert()
-		return inverseMatrix.Apply(float64(posX), float64(posY))
-	} else {
-		// When scaling it can happend that matrix is not invertable
-		return math.NaN(), math.NaN()
+	m, err := load(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("tiled: %s: %w", path, err)
 	}
+	return m, nil
 }
 
-func (c *Camera) Reset() {
-	c.Position[0] = 0
-	c.Position[1] = 0
-	c.Rotation = 0
-	c.ZoomFactor = 0
-}
-
-func NewGame() *Game {
-	f, err := os.Open("tiled/map1.tmx")
+// Load reads a TMX document. External tilesets and images are found relative to dir.
+func Load(r io.Reader, dir string) (*Map, error) {
+	m, err := load(r, dir)
 	if err != nil {
-		panic(err)
-	}
-	var map1 Map
-	if err = xml.NewDecoder(f).Decode(&map1); err != nil {
-		panic(err)
-	}
-	if err = f.Close(); err != nil {
-		panic(err)
+		return nil, fmt.Errorf("tiled: %w", err)
 	}
+	return m, nil
+}
 
-	lines := strings.Split(strings.TrimSpace(map1.Layer.Data.Text), "\n")
-	for i, line := range lines {
-		map1.MapLayer = append(map1.MapLayer, []int{})
-		items := strings.Split(strings.TrimSuffix(line, ","), ",")
-		for _, item := range items {
-			tileIndex, err := strconv.Atoi(item)
-			if err != nil {
-				fmt.Println("Line:", line, "Item:", item)
-				panic(err)
-			}
-			map1.MapLayer[i] = append(map1.MapLayer[i], tileIndex)
-		}
+func load(r io.Reader, dir string) (*Map, error) {
+	m := &Map{dir: dir}
+	if err := xml.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
 	}
 
-	lookup := make(map[int]image.Image)
-
-	for _, entry := range map1.TileSets {
-		f, err = os.Open("tiled/" + entry.Source)
-		var tileset TileSet
-		if err = xml.NewDecoder(f).Decode(&tileset); err != nil {
-			panic(err)
+	for i, ts := range m.TileSets {
+		if ts.Source == "" {
+			ts.dir = dir
+			continue
 		}
-		if err = f.Close(); err != nil {
-			panic(err)
-		}
-
-		if f, err = os.Open("tiled/" + tileset.Image.Source); err != nil {
-			panic(err)
-		}
-		img, _, err := image.Decode(f)
+		external, err := loadTileSet(filepath.Join(dir, ts.Source))
 		if err != nil {
-			log.Fatal(err)
-		}
-		if err = f.Close(); err != nil {
-			panic(err)
-		}
-		tilesImage := ebiten.NewImageFromImage(img)
-
-		index := entry.Firstgid
-
-		for y := 0; y < tileset.Image.Height; y += map1.TileHeight {
-			for x := 0; x < tileset.Image.Width; x += map1.TileWidth {
-				lookup[index] = tilesImage.SubImage(image.Rect(x, y, x+map1.TileWidth, y+map1.TileHeight))
-				index++
-			}
+			return nil, err
 		}
+		external.FirstGID = ts.FirstGID
+		external.Source = ts.Source
+		m.TileSets[i] = external
 	}
 
-	space := cp.NewSpace()
-
-	for _, object := range map1.ObjectGroups.Object {
-		cpebiten.AddStaticBox(space, cp.Vector{object.X + object.Width/2, object.Y + object.Height/2}, object.Width, object.Height)
-	}
-
-	worldWidth, worldHeight := map1.Width*map1.TileHeight, map1.Height*map1.TileWidth
-	world := ebiten.NewImage(worldWidth, worldHeight)
-
-	return &Game{
-		Game:    cpebiten.NewGame(space, 60),
-		map1:    map1,
-		tileSet: lookup,
-		world:   world,
-		camera: Camera{
-			ViewPort:   f64.Vec2{float64(worldWidth), float64(worldHeight)},
-			Position:   f64.Vec2{-100, -70},
-			ZoomFactor: 100,
-			Rotation:   0,
-		},
-	}
-}
-
-func (g *Game) Update() error {
-	if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		g.camera.Position[0] -= 1
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyRight) {
-		g.camera.Position[0] += 1
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyUp) {
-		g.camera.Position[1] -= 1
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyDown) {
-		g.camera.Position[1] += 1
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeyQ) {
-		g.camera.ZoomFactor -= 1
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyE) {
-		g.camera.ZoomFactor += 1
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeyR) {
-		g.camera.Rotation += 1
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeySpace) {
-		g.camera.Reset()
+	if err := eachLayer(m.Layers, func(layer *Layer) error {
+		return layer.decode()
+	}); err != nil {
+		return nil, err
 	}
-
-	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
-		g.drawPhysics = !g.drawPhysics
-	}
-
-	if err := g.Game.Update(); err != nil {
-		return err
-	}
-
-	return nil
+	return m, nil
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	screen.Fill(color.Black)
-
-	op := &ebiten.DrawImageOptions{}
-	op.ColorM.Scale(200.0/255.0, 200.0/255.0, 200.0/255.0, 1)
-
-	for x := 0; x < g.map1.Width; x++ {
-		for y := 0; y < g.map1.Height; y++ {
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(float64(x*g.map1.TileWidth), float64(y*g.map1.TileHeight))
-			tile := g.map1.MapLayer[y][x]
-			img := g.tileSet[tile]
-			if img == nil {
-				panic("image nil at tile " + fmt.Sprint(tile))
+// TileSetFor finds the tileset a global tile id belongs to, and the tile's id within it,
+// ignoring any flip flags. It returns nil for the empty tile 0 or an id past the end of the
+// last tileset.
+func (m *Map) TileSetFor(gid uint32) (*TileSet, int) {
+	gid, _ = SplitGID(gid)
+	if gid == 0 {
+		return nil, 0
+	}
+	for i := len(m.TileSets) - 1; i >= 0; i-- {
+		ts := m.TileSets[i]
+		if ts.FirstGID <= gid {
+			id := int(gid - ts.FirstGID)
+			if ts.TileCount > 0 && id >= ts.TileCount {
+				return nil, 0
 			}
-			g.world.DrawImage(img.(*ebiten.Image), op)
+			return ts, id
 		}
 	}
+	return nil, 0
+}
 
-	if g.drawPhysics {
-		op := cpebiten.NewDrawOptions(g.world)
-		cp.DrawSpace(g.Game.Space, op)
-		op.Flush()
+// Path resolves the source of an image layer's image.
+func (m *Map) Path(img *Image) string {
+	if img == nil {
+		return ""
 	}
-
-	g.camera.Render(g.world, screen)
-
-	ebitenutil.DebugPrint(screen, fmt.Sprintf("TPS: %0.2f FPS: %0.2f", ebiten.CurrentTPS(), ebiten.CurrentFPS()))
-	worldX, worldY := g.camera.ScreenToWorld(ebiten.CursorPosition())
-	ebitenutil.DebugPrint(
-		screen,
-		fmt.Sprintf("TPS: %0.2f\nMove (WASD/Arrows)\nZoom (QE)\nRotate (R)\nReset (Space)", ebiten.CurrentTPS()),
-	)
-	ebitenutil.DebugPrintAt(
-		screen,
-		fmt.Sprintf("%s\nCursor World Pos: %.2f,%.2f",
-			g.camera.String(),
-			worldX, worldY),
-		0, screenHeight-32,
-	)
+	if filepath.IsAbs(img.Source) {
+		return img.Source
+	}
+	return filepath.Join(m.dir, img.Source)
 }
 
-func (g *Game) Layout(int, int) (int, int) {
-	return screenWidth, screenHeight
+// AllLayers returns every layer, with the layers inside groups flattened, in draw order.
+func (m *Map) AllLayers() []*Layer {
+	var layers []*Layer
+	_ = eachLayer(m.Layers, func(layer *Layer) error {
+		layers = append(layers, layer)
+		return nil
+	})
+	return layers
 }
 
-const screenWidth, screenHeight = 800, 600
-
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Tumble")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
+// eachLayer calls f for every layer in draw order, descending into groups after the group
+// itself, and stops at the first error.
+func eachLayer(layers []*Layer, f func(layer *Layer) error) error {
+	for _, layer := range layers {
+		if err := f(layer); err != nil {
+			return err
+		}
+		if err := eachLayer(layer.Layers, f); err != nil {
+			return err
+		}
 	}
+	return nil
 }
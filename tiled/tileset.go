@@ -0,0 +1,139 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+)
+
+// TileSet is a TSX document, or a tileset embedded in a map.
+type TileSet struct {
+	// FirstGID is the global id of the tileset's first tile in the map that uses it.
+	FirstGID uint32 `xml:"firstgid,attr"`
+	// Source is the TSX file an external tileset was loaded from, relative to the map.
+	Source string `xml:"source,attr"`
+
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+
+	Properties Properties `xml:"properties>property"`
+
+	// Image holds every tile, unless this is a collection of images where each Tile has its
+	// own.
+	Image *Image `xml:"image"`
+
+	// Tiles are the tiles with extra information such as properties. Plain tiles of an image
+	// aren't listed.
+	Tiles []*Tile `xml:"tile"`
+
+	// dir is where image sources are relative to
+	dir string
+}
+
+// Image is a picture used by a tileset, tile or image layer.
+type Image struct {
+	Source string `xml:"source,attr"`
+	// Trans is a color, like "ff00ff", to treat as transparent.
+	Trans  string `xml:"trans,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+// Tile is a tile in a tileset that has properties or its own image.
+type Tile struct {
+	ID         int        `xml:"id,attr"`
+	Type       string     `xml:"type,attr"`
+	Properties Properties `xml:"properties>property"`
+	Image      *Image     `xml:"image"`
+	// ObjectGroup holds the collision shapes drawn on the tile, relative to its top left
+	// corner.
+	ObjectGroup *Layer `xml:"objectgroup"`
+	// Animation is the frames the tile cycles through wherever it is placed, if it is
+	// animated.
+	Animation []Frame `xml:"animation>frame"`
+}
+
+// Frame is one step of a tile's animation.
+type Frame struct {
+	// TileID is the tile shown, from the same tileset.
+	TileID int `xml:"tileid,attr"`
+	// Duration is how long it is shown for in milliseconds.
+	Duration int `xml:"duration,attr"`
+}
+
+// LoadTileSetFile reads a TSX file.
+func LoadTileSetFile(path string) (*TileSet, error) {
+	ts, err := loadTileSet(path)
+	if err != nil {
+		return nil, fmt.Errorf("tiled: %w", err)
+	}
+	return ts, nil
+}
+
+func loadTileSet(path string) (*TileSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ts := &TileSet{}
+	if err := xml.NewDecoder(f).Decode(ts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ts.dir = filepath.Dir(path)
+	return ts, nil
+}
+
+// Path resolves the source of one of the tileset's images.
+func (ts *TileSet) Path(img *Image) string {
+	if img == nil {
+		return ""
+	}
+	if filepath.IsAbs(img.Source) {
+		return img.Source
+	}
+	return filepath.Join(ts.dir, img.Source)
+}
+
+// Tile returns the extra information for a tile, or nil if it has none.
+func (ts *TileSet) Tile(id int) *Tile {
+	for _, tile := range ts.Tiles {
+		if tile.ID == id {
+			return tile
+		}
+	}
+	return nil
+}
+
+// TileSize returns the size of a tile's image, which is the tileset's tile size unless it is a
+// collection of images.
+func (ts *TileSet) TileSize(id int) (int, int) {
+	if ts.Image == nil {
+		if tile := ts.Tile(id); tile != nil && tile.Image != nil {
+			return tile.Image.Width, tile.Image.Height
+		}
+	}
+	return ts.TileWidth, ts.TileHeight
+}
+
+// TileRect returns where a tile is in the tileset's image.
+func (ts *TileSet) TileRect(id int) image.Rectangle {
+	columns := ts.Columns
+	if columns == 0 && ts.Image != nil {
+		columns = (ts.Image.Width - 2*ts.Margin + ts.Spacing) / (ts.TileWidth + ts.Spacing)
+	}
+	if columns == 0 {
+		return image.Rectangle{}
+	}
+	x := ts.Margin + (id%columns)*(ts.TileWidth+ts.Spacing)
+	y := ts.Margin + (id/columns)*(ts.TileHeight+ts.Spacing)
+	return image.Rect(x, y, x+ts.TileWidth, y+ts.TileHeight)
+}
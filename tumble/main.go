@@ -0,0 +1,19 @@
+//go:build !headless
+
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func main() {
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Tumble")
+	if err := ebiten.RunGame(NewGame(rand.New(rand.NewSource(time.Now().UnixNano())))); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,10 +1,8 @@
 package main
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/jakecoffman/cp"
 	"github.com/jakecoffman/cpebiten"
-	"log"
 	"math/rand"
 )
 
@@ -13,7 +11,8 @@ const (
 	screenHeight = 480
 )
 
-func NewGame() *cpebiten.Game {
+// NewGame builds the scene, taking its randomness from rng.
+func NewGame(rng *rand.Rand) *cpebiten.Game {
 	space := cp.NewSpace()
 	space.SetGravity(cp.Vector{0, 600})
 
@@ -39,7 +38,7 @@ func NewGame() *cpebiten.Game {
 		for j := 0; j < 3; j++ {
 			pos := cp.Vector{float64(i)*width + 200, float64(j)*height + 100}
 
-			typ := rand.Intn(3)
+			typ := rng.Intn(3)
 			if typ == 0 {
 				cpebiten.AddBox(space, pos, mass, width, height)
 			} else if typ == 1 {
@@ -53,11 +52,3 @@ func NewGame() *cpebiten.Game {
 
 	return cpebiten.NewGame(space, 180)
 }
-
-func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("Tumble")
-	if err := ebiten.RunGame(NewGame()); err != nil {
-		log.Fatal(err)
-	}
-}